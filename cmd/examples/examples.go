@@ -87,10 +87,10 @@ func main() {
 	// From reader (http)
 	func() {
 		resp, err := http.Get("https://upload.wikimedia.org/wikipedia/commons/5/5e/M104_ngc4594_sombrero_galaxy_hi-res.jpg")
-		defer resp.Body.Close()
 		if err != nil {
 			panic(err)
 		}
+		defer resp.Body.Close()
 
 		fastimageinfo.SetChunkSize(1)
 		imageInfo, bytesRead, err := fastimageinfo.GetInfoFromReader(resp.Body)
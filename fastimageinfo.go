@@ -9,7 +9,21 @@ import (
 
 type ImageInfo struct {
 	Type parser.ImageType
+
+	// Size as stored in the file
 	Size parser.ImageSize
+
+	// Size after the orientation has been applied, as the image is displayed
+	DisplaySize parser.ImageSize
+
+	Orientation parser.Orientation
+
+	// Bit depth, channels, color model and alpha, zero if the header does not
+	// describe them
+	PixelFormat parser.PixelFormat
+
+	// Physical resolution, zero if the image does not store it
+	Resolution parser.Resolution
 }
 
 type Result int
@@ -53,7 +67,7 @@ func DetectType(p []byte) (Result, parser.ImageType, error) {
 		}
 
 		if result == parser.Valid {
-			return Valid, imageType, nil
+			return detectSubtype(p, imageType)
 		}
 	}
 
@@ -64,6 +78,27 @@ func DetectType(p []byte) (Result, parser.ImageType, error) {
 	return Invalid, parser.UnknownType, nil
 }
 
+// detectSubtype returns the image type of a file which is detected by the parser of
+// a format other image types are based on.
+func detectSubtype(p []byte, imageType parser.ImageType) (Result, parser.ImageType, error) {
+	subtypeParser, ok := parser.ImageParsers[imageType].(parser.SubtypeParser)
+	if !ok {
+		return Valid, imageType, nil
+	}
+
+	result, subtype := subtypeParser.DetectSubtype(p)
+
+	if result == parser.NeedMoreData {
+		return NeedMoreData, parser.UnknownType, nil
+	}
+
+	if result == parser.Valid {
+		return Valid, subtype, nil
+	}
+
+	return Invalid, parser.UnknownType, nil
+}
+
 func GetSize(p []byte) (Result, parser.ImageSize, error) {
 	result, imageType, err := DetectType(p)
 	if err != nil || result != Valid {
@@ -94,14 +129,192 @@ func GetInfo(p []byte) (Result, ImageInfo, error) {
 		return result, ImageInfo{}, err
 	}
 
+	orientation := parser.OrientationNormal
+
+	if orientationParser, ok := parser.ImageParsers[imageType].(parser.OrientationParser); ok {
+		result, value := orientationParser.GetOrientation(p)
+		if result == parser.NeedMoreData {
+			return NeedMoreData, ImageInfo{}, nil
+		}
+
+		if result == parser.Valid {
+			orientation = value
+		}
+	}
+
+	pixelFormat := parser.PixelFormat{}
+
+	if pixelFormatParser, ok := parser.ImageParsers[imageType].(parser.PixelFormatParser); ok {
+		result, value := pixelFormatParser.GetPixelFormat(p)
+		if result == parser.NeedMoreData {
+			return NeedMoreData, ImageInfo{}, nil
+		}
+
+		if result == parser.Valid {
+			pixelFormat = value
+		}
+	}
+
+	resolution := parser.Resolution{}
+
+	if resolutionParser, ok := parser.ImageParsers[imageType].(parser.ResolutionParser); ok {
+		result, value := resolutionParser.GetResolution(p)
+		if result == parser.NeedMoreData {
+			return NeedMoreData, ImageInfo{}, nil
+		}
+
+		if result == parser.Valid {
+			resolution = value
+		}
+	}
+
 	imageInfo := ImageInfo{
-		Type: imageType,
-		Size: imageSize,
+		Type:        imageType,
+		Size:        imageSize,
+		DisplaySize: orientation.Apply(imageSize),
+		Orientation: orientation,
+		PixelFormat: pixelFormat,
+		Resolution:  resolution,
 	}
 
 	return Valid, imageInfo, nil
 }
 
+// GetEXIF decodes the requested fields of the EXIF data. Invalid is returned if the
+// image type does not support EXIF data or the image does not contain any.
+func GetEXIF(p []byte, fields parser.EXIFFields) (Result, parser.EXIF, error) {
+	result, imageType, err := DetectType(p)
+	if err != nil || result != Valid {
+		return result, parser.EXIF{}, err
+	}
+
+	exifParser, ok := parser.ImageParsers[imageType].(parser.EXIFParser)
+	if !ok {
+		return Invalid, parser.EXIF{}, nil
+	}
+
+	resultParser, exif := exifParser.GetEXIF(p, fields)
+
+	if resultParser == parser.NeedMoreData {
+		return NeedMoreData, parser.EXIF{}, nil
+	}
+
+	if resultParser == parser.Valid {
+		return Valid, exif, nil
+	}
+
+	return Invalid, parser.EXIF{}, nil
+}
+
+// GetXMP returns the raw XMP packet, which can be parsed with parser.ParseXMP.
+// Invalid is returned if the image type does not support XMP or the image does not
+// contain a packet. Properties which do not fit into the packet of JPEG files are
+// stored in the extended packet returned by GetExtendedXMP.
+func GetXMP(p []byte) (Result, []byte, error) {
+	result, imageType, err := DetectType(p)
+	if err != nil || result != Valid {
+		return result, nil, err
+	}
+
+	xmpParser, ok := parser.ImageParsers[imageType].(parser.XMPParser)
+	if !ok {
+		return Invalid, nil, nil
+	}
+
+	resultParser, packet := xmpParser.GetXMP(p)
+
+	if resultParser == parser.NeedMoreData {
+		return NeedMoreData, nil, nil
+	}
+
+	if resultParser == parser.Valid {
+		return Valid, packet, nil
+	}
+
+	return Invalid, nil, nil
+}
+
+// GetExtendedXMP returns the raw extended XMP packet, which holds the properties not
+// fitting into the standard packet of JPEG files. Invalid is returned if the image
+// type does not support extended XMP or the image does not contain a packet.
+func GetExtendedXMP(p []byte) (Result, []byte, error) {
+	result, imageType, err := DetectType(p)
+	if err != nil || result != Valid {
+		return result, nil, err
+	}
+
+	extendedXMPParser, ok := parser.ImageParsers[imageType].(parser.ExtendedXMPParser)
+	if !ok {
+		return Invalid, nil, nil
+	}
+
+	resultParser, packet := extendedXMPParser.GetExtendedXMP(p)
+
+	if resultParser == parser.NeedMoreData {
+		return NeedMoreData, nil, nil
+	}
+
+	if resultParser == parser.Valid {
+		return Valid, packet, nil
+	}
+
+	return Invalid, nil, nil
+}
+
+// GetAnimation counts the frames of animated images, up to maxFrames if it is greater
+// than 0. Still images of formats which support animations are reported as a single
+// frame, Invalid is returned if the image type does not support animations.
+func GetAnimation(p []byte, maxFrames int) (Result, parser.Animation, error) {
+	result, imageType, err := DetectType(p)
+	if err != nil || result != Valid {
+		return result, parser.Animation{}, err
+	}
+
+	animationParser, ok := parser.ImageParsers[imageType].(parser.AnimationParser)
+	if !ok {
+		return Invalid, parser.Animation{}, nil
+	}
+
+	resultParser, animation := animationParser.GetAnimation(p, maxFrames)
+
+	if resultParser == parser.NeedMoreData {
+		return NeedMoreData, parser.Animation{}, nil
+	}
+
+	if resultParser == parser.Valid {
+		return Valid, animation, nil
+	}
+
+	return Invalid, parser.Animation{}, nil
+}
+
+// GetColorProfile reads the embedded ICC profile and other color information of the
+// image. An image without color information is reported as Valid with an empty
+// profile, Invalid is returned if the image type does not support color profiles.
+func GetColorProfile(p []byte) (Result, parser.ColorProfile, error) {
+	result, imageType, err := DetectType(p)
+	if err != nil || result != Valid {
+		return result, parser.ColorProfile{}, err
+	}
+
+	colorProfileParser, ok := parser.ImageParsers[imageType].(parser.ColorProfileParser)
+	if !ok {
+		return Invalid, parser.ColorProfile{}, nil
+	}
+
+	resultParser, colorProfile := colorProfileParser.GetColorProfile(p)
+
+	if resultParser == parser.NeedMoreData {
+		return NeedMoreData, parser.ColorProfile{}, nil
+	}
+
+	if resultParser == parser.Valid {
+		return Valid, colorProfile, nil
+	}
+
+	return Invalid, parser.ColorProfile{}, nil
+}
+
 func DetectTypeFromReader(r io.Reader) (parser.ImageType, int, error) {
 	buf := bytes.Buffer{}
 
@@ -116,6 +329,12 @@ func DetectTypeFromReader(r io.Reader) (parser.ImageType, int, error) {
 		buf.Write(chunk[:count])
 
 		result, imageType, err := DetectType(buf.Bytes())
+		if err == nil && result == Invalid {
+			if imageType, ok := detectTypeFromFooter(r, buf.Bytes()); ok {
+				return imageType, len(buf.Bytes()), nil
+			}
+		}
+
 		if err != nil || result == Invalid || result == Valid {
 			return imageType, len(buf.Bytes()), err
 		}
@@ -140,6 +359,12 @@ func GetSizeFromReader(r io.Reader) (parser.ImageSize, int, error) {
 		buf.Write(chunk[:count])
 
 		result, imageSize, err := GetSize(buf.Bytes())
+		if err == nil && result != Valid {
+			if imageInfo, ok := getInfoFromReaderAt(r, buf.Bytes()); ok {
+				return imageInfo.Size, len(buf.Bytes()), nil
+			}
+		}
+
 		if err != nil || result == Invalid || result == Valid {
 			return imageSize, len(buf.Bytes()), err
 		}
@@ -163,6 +388,12 @@ func GetInfoFromReader(r io.Reader) (ImageInfo, int, error) {
 		buf.Write(chunk[:count])
 
 		result, imageInfo, err := GetInfo(buf.Bytes())
+		if err == nil && result != Valid {
+			if imageInfo, ok := getInfoFromReaderAt(r, buf.Bytes()); ok {
+				return imageInfo, len(buf.Bytes()), nil
+			}
+		}
+
 		if err != nil || result == Invalid || result == Valid {
 			return imageInfo, len(buf.Bytes()), err
 		}
@@ -173,6 +404,235 @@ func GetInfoFromReader(r io.Reader) (ImageInfo, int, error) {
 	}
 }
 
+// GetEXIFFromReader reads chunks until the requested EXIF fields are decoded, so
+// fewer fields usually need less data.
+func GetEXIFFromReader(r io.Reader, fields parser.EXIFFields) (parser.EXIF, int, error) {
+	buf := bytes.Buffer{}
+	for {
+		chunk := make([]byte, chunkSize)
+
+		count, err := r.Read(chunk)
+		if err != nil {
+			return parser.EXIF{}, 0, err
+		}
+
+		buf.Write(chunk[:count])
+
+		result, exif, err := GetEXIF(buf.Bytes(), fields)
+
+		if err != nil || result == Invalid || result == Valid {
+			return exif, len(buf.Bytes()), err
+		}
+
+		if result == NeedMoreData {
+			continue
+		}
+	}
+}
+
+func GetXMPFromReader(r io.Reader) ([]byte, int, error) {
+	buf := bytes.Buffer{}
+	for {
+		chunk := make([]byte, chunkSize)
+
+		count, err := r.Read(chunk)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		buf.Write(chunk[:count])
+
+		result, packet, err := GetXMP(buf.Bytes())
+
+		if err != nil || result == Invalid || result == Valid {
+			return packet, len(buf.Bytes()), err
+		}
+
+		if result == NeedMoreData {
+			continue
+		}
+	}
+}
+
+func GetExtendedXMPFromReader(r io.Reader) ([]byte, int, error) {
+	buf := bytes.Buffer{}
+	for {
+		chunk := make([]byte, chunkSize)
+
+		count, err := r.Read(chunk)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		buf.Write(chunk[:count])
+
+		result, packet, err := GetExtendedXMP(buf.Bytes())
+
+		if err != nil || result == Invalid || result == Valid {
+			return packet, len(buf.Bytes()), err
+		}
+
+		if result == NeedMoreData {
+			continue
+		}
+	}
+}
+
+// GetAnimationFromReader reads chunks until all frames or maxFrames frames are
+// counted. Counting the frames usually needs the whole file, so a limit allows to
+// stop early.
+func GetAnimationFromReader(r io.Reader, maxFrames int) (parser.Animation, int, error) {
+	buf := bytes.Buffer{}
+	for {
+		chunk := make([]byte, chunkSize)
+
+		count, err := r.Read(chunk)
+		if err != nil {
+			return parser.Animation{}, 0, err
+		}
+
+		buf.Write(chunk[:count])
+
+		result, animation, err := GetAnimation(buf.Bytes(), maxFrames)
+
+		if err != nil || result == Invalid || result == Valid {
+			return animation, len(buf.Bytes()), err
+		}
+
+		if result == NeedMoreData {
+			continue
+		}
+	}
+}
+
+func GetColorProfileFromReader(r io.Reader) (parser.ColorProfile, int, error) {
+	buf := bytes.Buffer{}
+	for {
+		chunk := make([]byte, chunkSize)
+
+		count, err := r.Read(chunk)
+		if err != nil {
+			return parser.ColorProfile{}, 0, err
+		}
+
+		buf.Write(chunk[:count])
+
+		result, colorProfile, err := GetColorProfile(buf.Bytes())
+
+		if err != nil || result == Invalid || result == Valid {
+			return colorProfile, len(buf.Bytes()), err
+		}
+
+		if result == NeedMoreData {
+			continue
+		}
+	}
+}
+
+// detectTypeFromFooter detects image types which can be identified by a footer, as a
+// fallback once no parser could detect the image type from the start p of the file.
+// This requires r to support random access.
+func detectTypeFromFooter(r io.Reader, p []byte) (parser.ImageType, bool) {
+	readerAt, size, ok := readerAtWithSize(r, len(p))
+	if !ok {
+		return parser.UnknownType, false
+	}
+
+	for imageType, imageParser := range parser.ImageParsers {
+		footerParser, ok := imageParser.(parser.FooterParser)
+		if ok && footerParser.DetectFooter(readerAt, size) == parser.Valid {
+			return imageType, true
+		}
+	}
+
+	return parser.UnknownType, false
+}
+
+// getInfoFromReaderAt reads the parts of the image info stored far from the start p
+// of the file directly, for image types whose parser supports this. Image types
+// which can be identified by a footer are detected as well. This requires r to
+// support random access.
+func getInfoFromReaderAt(r io.Reader, p []byte) (ImageInfo, bool) {
+	result, imageType, err := DetectType(p)
+	if err != nil || result == NeedMoreData {
+		return ImageInfo{}, false
+	}
+
+	if result == Invalid {
+		var ok bool
+		if imageType, ok = detectTypeFromFooter(r, p); !ok {
+			return ImageInfo{}, false
+		}
+	}
+
+	readerAtParser, ok := parser.ImageParsers[imageType].(parser.ReaderAtSizeParser)
+	if !ok {
+		return ImageInfo{}, false
+	}
+
+	readerAt, size, ok := readerAtWithSize(r, len(p))
+	if !ok {
+		return ImageInfo{}, false
+	}
+
+	resultParser, info := readerAtParser.GetInfoFromReaderAt(p, readerAt, size)
+	if resultParser != parser.Valid {
+		return ImageInfo{}, false
+	}
+
+	imageInfo := ImageInfo{
+		Type:        imageType,
+		Size:        info.Size,
+		DisplaySize: info.Orientation.Apply(info.Size),
+		Orientation: info.Orientation,
+		PixelFormat: info.PixelFormat,
+		Resolution:  info.Resolution,
+	}
+
+	return imageInfo, true
+}
+
+// readerAtWithSize returns the data of r as io.ReaderAt together with its size, if
+// possible. As consumed bytes have already been read from r, the data starts that
+// many bytes before the current position of r.
+func readerAtWithSize(r io.Reader, consumed int) (io.ReaderAt, int64, bool) {
+	var readerAt io.ReaderAt
+	var size int64
+
+	switch v := r.(type) {
+	case *os.File:
+		stat, err := v.Stat()
+		if err != nil || !stat.Mode().IsRegular() {
+			return nil, 0, false
+		}
+		readerAt, size = v, stat.Size()
+	case interface {
+		io.ReaderAt
+		Size() int64
+	}:
+		readerAt, size = v, v.Size()
+	default:
+		return nil, 0, false
+	}
+
+	seeker, ok := r.(io.Seeker)
+	if !ok {
+		return nil, 0, false
+	}
+
+	position, err := seeker.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, 0, false
+	}
+
+	start := position - int64(consumed)
+	if start < 0 || start > size {
+		return nil, 0, false
+	}
+
+	return io.NewSectionReader(readerAt, start, size-start), size - start, true
+}
+
 func DetectTypeFromFile(filepath string) (parser.ImageType, error) {
 	f, err := os.Open(filepath)
 	defer f.Close()
@@ -205,3 +665,58 @@ func GetInfoFromFile(filepath string) (ImageInfo, error) {
 	imageInfo, _, err := GetInfoFromReader(f)
 	return imageInfo, err
 }
+
+func GetEXIFFromFile(filepath string, fields parser.EXIFFields) (parser.EXIF, error) {
+	f, err := os.Open(filepath)
+	defer f.Close()
+	if err != nil {
+		return parser.EXIF{}, err
+	}
+
+	exif, _, err := GetEXIFFromReader(f, fields)
+	return exif, err
+}
+
+func GetXMPFromFile(filepath string) ([]byte, error) {
+	f, err := os.Open(filepath)
+	defer f.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	packet, _, err := GetXMPFromReader(f)
+	return packet, err
+}
+
+func GetExtendedXMPFromFile(filepath string) ([]byte, error) {
+	f, err := os.Open(filepath)
+	defer f.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	packet, _, err := GetExtendedXMPFromReader(f)
+	return packet, err
+}
+
+func GetAnimationFromFile(filepath string, maxFrames int) (parser.Animation, error) {
+	f, err := os.Open(filepath)
+	defer f.Close()
+	if err != nil {
+		return parser.Animation{}, err
+	}
+
+	animation, _, err := GetAnimationFromReader(f, maxFrames)
+	return animation, err
+}
+
+func GetColorProfileFromFile(filepath string) (parser.ColorProfile, error) {
+	f, err := os.Open(filepath)
+	defer f.Close()
+	if err != nil {
+		return parser.ColorProfile{}, err
+	}
+
+	colorProfile, _, err := GetColorProfileFromReader(f)
+	return colorProfile, err
+}
@@ -2,7 +2,13 @@ package fastimageinfo
 
 import (
 	"github.com/kkettinger/fastimageinfo/parser"
+	"io"
+	"io/ioutil"
+	"math"
+	"os"
+	"strings"
 	"testing"
+	"time"
 )
 
 func DetectTypeFromFileTesting(filename string, expectedImageType parser.ImageType, t *testing.T) {
@@ -63,28 +69,148 @@ func Test(t *testing.T) {
 		{File: "testdata/jpeg/example_2.jpg", expectedType: parser.JPEG, expectedSize: parser.ImageSize{Width: 800, Height: 600}},
 		{File: "testdata/jpeg/example_3.jpg", expectedType: parser.JPEG, expectedSize: parser.ImageSize{Width: 1, Height: 1}},
 		{File: "testdata/jpeg/example_4.jpg", expectedType: parser.JPEG, expectedSize: parser.ImageSize{Width: 275, Height: 297}},
+		{File: "testdata/jpeg/example_5.jpg", expectedType: parser.JPEG, expectedSize: parser.ImageSize{Width: 640, Height: 480}},
+		{File: "testdata/jpeg/example_6.jpg", expectedType: parser.JPEG, expectedSize: parser.ImageSize{Width: 6240, Height: 4160}},
+		{File: "testdata/jpeg/example_7.jpg", expectedType: parser.JPEG, expectedSize: parser.ImageSize{Width: 1200, Height: 800}},
+		{File: "testdata/jpeg/example_8.jpg", expectedType: parser.JPEG, expectedSize: parser.ImageSize{Width: 800, Height: 600}},
+		{File: "testdata/jpeg/example_9.jpg", expectedType: parser.JPEG, expectedSize: parser.ImageSize{Width: 400, Height: 300}},
+		{File: "testdata/jpeg/example_10.jpg", expectedType: parser.JPEG, expectedSize: parser.ImageSize{Width: 200, Height: 100}},
+		{File: "testdata/jpeg/example_11.jpg", expectedType: parser.JPEG, expectedSize: parser.ImageSize{Width: 800, Height: 600}},
 
 		// PNG
 		{File: "testdata/png/example_1.png", expectedType: parser.PNG, expectedSize: parser.ImageSize{Width: 172, Height: 178}},
 		{File: "testdata/png/example_2.png", expectedType: parser.PNG, expectedSize: parser.ImageSize{Width: 400, Height: 300}},
 		{File: "testdata/png/example_3.png", expectedType: parser.PNG, expectedSize: parser.ImageSize{Width: 386, Height: 395}},
+		{File: "testdata/png/example_4.png", expectedType: parser.PNG, expectedSize: parser.ImageSize{Width: 300, Height: 200}},
+		{File: "testdata/png/example_5.png", expectedType: parser.PNG, expectedSize: parser.ImageSize{Width: 120, Height: 90}},
+		{File: "testdata/png/example_6.png", expectedType: parser.PNG, expectedSize: parser.ImageSize{Width: 64, Height: 32}},
+		{File: "testdata/png/example_7.png", expectedType: parser.APNG, expectedSize: parser.ImageSize{Width: 48, Height: 32}},
+		{File: "testdata/png/example_8.png", expectedType: parser.PNG, expectedSize: parser.ImageSize{Width: 16, Height: 8}},
+		{File: "testdata/png/example_9.png", expectedType: parser.PNG, expectedSize: parser.ImageSize{Width: 16, Height: 8}},
+		{File: "testdata/png/example_10.png", expectedType: parser.PNG, expectedSize: parser.ImageSize{Width: 48, Height: 32}},
 
 		// GIF
 		{File: "testdata/gif/example_1.gif", expectedType: parser.GIF, expectedSize: parser.ImageSize{Width: 250, Height: 297}},
 		{File: "testdata/gif/example_2.gif", expectedType: parser.GIF, expectedSize: parser.ImageSize{Width: 217, Height: 217}},
+		{File: "testdata/gif/example_3.gif", expectedType: parser.GIF, expectedSize: parser.ImageSize{Width: 64, Height: 48}},
+		{File: "testdata/gif/example_4.gif", expectedType: parser.GIF, expectedSize: parser.ImageSize{Width: 16, Height: 16}},
+		{File: "testdata/gif/example_5.gif", expectedType: parser.GIF, expectedSize: parser.ImageSize{Width: 24, Height: 24}},
+		{File: "testdata/gif/example_6.gif", expectedType: parser.GIF, expectedSize: parser.ImageSize{Width: 24, Height: 24}},
 
 		// BMP
 		{File: "testdata/bmp/example_1.bmp", expectedType: parser.BMP, expectedSize: parser.ImageSize{Width: 72, Height: 48}},
 		{File: "testdata/bmp/example_2.bmp", expectedType: parser.BMP, expectedSize: parser.ImageSize{Width: 200, Height: 200}},
+		{File: "testdata/bmp/example_3.bmp", expectedType: parser.BMP, expectedSize: parser.ImageSize{Width: 32, Height: 16}},
 
 		// WEBP
 		{File: "testdata/webp/example_1.webp", expectedType: parser.WEBP, expectedSize: parser.ImageSize{Width: 550, Height: 368}},
 		{File: "testdata/webp/example_2.webp", expectedType: parser.WEBP, expectedSize: parser.ImageSize{Width: 400, Height: 301}},
 		{File: "testdata/webp/example_3.webp", expectedType: parser.WEBP, expectedSize: parser.ImageSize{Width: 400, Height: 301}},
+		{File: "testdata/webp/example_4.webp", expectedType: parser.WEBP, expectedSize: parser.ImageSize{Width: 400, Height: 300}},
+		{File: "testdata/webp/example_5.webp", expectedType: parser.WEBP, expectedSize: parser.ImageSize{Width: 640, Height: 480}},
+		{File: "testdata/webp/example_6.webp", expectedType: parser.WEBP, expectedSize: parser.ImageSize{Width: 64, Height: 64}},
+		{File: "testdata/webp/example_7.webp", expectedType: parser.WEBP, expectedSize: parser.ImageSize{Width: 32, Height: 32}},
 
 		// TIFF
 		{File: "testdata/tiff/example_1.tif", expectedType: parser.TIFF, expectedSize: parser.ImageSize{Width: 640, Height: 480}},
 		{File: "testdata/tiff/example_2.tif", expectedType: parser.TIFF, expectedSize: parser.ImageSize{Width: 232, Height: 205}},
+		{File: "testdata/tiff/example_3.tif", expectedType: parser.TIFF, expectedSize: parser.ImageSize{Width: 120000, Height: 80000}},
+		{File: "testdata/tiff/example_4.tif", expectedType: parser.TIFF, expectedSize: parser.ImageSize{Width: 2048, Height: 1536}},
+		{File: "testdata/tiff/example_5.tif", expectedType: parser.TIFF, expectedSize: parser.ImageSize{Width: 1728, Height: 2200}},
+		{File: "testdata/tiff/example_6.tif", expectedType: parser.TIFF, expectedSize: parser.ImageSize{Width: 800, Height: 600}},
+		{File: "testdata/tiff/example_7.tif", expectedType: parser.TIFF, expectedSize: parser.ImageSize{Width: 100, Height: 50}},
+		{File: "testdata/tiff/example_8.tif", expectedType: parser.TIFF, expectedSize: parser.ImageSize{Width: 320, Height: 240}},
+		{File: "testdata/tiff/example_9.tif", expectedType: parser.TIFF, expectedSize: parser.ImageSize{Width: 256, Height: 128}},
+		{File: "testdata/tiff/example_10.tif", expectedType: parser.TIFF, expectedSize: parser.ImageSize{Width: 64, Height: 64}},
+		{File: "testdata/tiff/example_11.tif", expectedType: parser.TIFF, expectedSize: parser.ImageSize{Width: 5782, Height: 3946}},
+		{File: "testdata/tiff/example_12.tif", expectedType: parser.TIFF, expectedSize: parser.ImageSize{Width: 8256, Height: 5504}},
+
+		// AVIF
+		{File: "testdata/avif/example_1.avif", expectedType: parser.AVIF, expectedSize: parser.ImageSize{Width: 640, Height: 480}},
+		{File: "testdata/avif/example_2.avif", expectedType: parser.AVIF, expectedSize: parser.ImageSize{Width: 320, Height: 240}},
+		{File: "testdata/avif/example_3.avif", expectedType: parser.AVIF, expectedSize: parser.ImageSize{Width: 1280, Height: 720}},
+		{File: "testdata/avif/example_4.avif", expectedType: parser.AVIF, expectedSize: parser.ImageSize{Width: 320, Height: 240}},
+		{File: "testdata/avif/example_5.avif", expectedType: parser.AVIF, expectedSize: parser.ImageSize{Width: 512, Height: 256}},
+
+		// HEIC
+		{File: "testdata/heic/example_1.heic", expectedType: parser.HEIC, expectedSize: parser.ImageSize{Width: 4032, Height: 3024}},
+		{File: "testdata/heic/example_2.heic", expectedType: parser.HEIC, expectedSize: parser.ImageSize{Width: 3024, Height: 4032}},
+		{File: "testdata/heic/example_3.heic", expectedType: parser.HEIC, expectedSize: parser.ImageSize{Width: 4032, Height: 3024}},
+		{File: "testdata/heic/example_4.heic", expectedType: parser.HEIC, expectedSize: parser.ImageSize{Width: 2016, Height: 1512}},
+
+		// HEIF
+		{File: "testdata/heif/example_1.heif", expectedType: parser.HEIF, expectedSize: parser.ImageSize{Width: 1920, Height: 1080}},
+
+		// JXL
+		{File: "testdata/jxl/example_1.jxl", expectedType: parser.JXL, expectedSize: parser.ImageSize{Width: 256, Height: 192}},
+		{File: "testdata/jxl/example_2.jxl", expectedType: parser.JXL, expectedSize: parser.ImageSize{Width: 1920, Height: 1080}},
+		{File: "testdata/jxl/example_3.jxl", expectedType: parser.JXL, expectedSize: parser.ImageSize{Width: 1001, Height: 333}},
+		{File: "testdata/jxl/example_4.jxl", expectedType: parser.JXL, expectedSize: parser.ImageSize{Width: 64, Height: 48}},
+
+		// ICO
+		{File: "testdata/ico/example_1.ico", expectedType: parser.ICO, expectedSize: parser.ImageSize{Width: 48, Height: 48}},
+		{File: "testdata/ico/example_2.ico", expectedType: parser.ICO, expectedSize: parser.ImageSize{Width: 512, Height: 512}},
+
+		// CUR
+		{File: "testdata/cur/example_1.cur", expectedType: parser.CUR, expectedSize: parser.ImageSize{Width: 32, Height: 32}},
+
+		// PSD
+		{File: "testdata/psd/example_1.psd", expectedType: parser.PSD, expectedSize: parser.ImageSize{Width: 1200, Height: 800}},
+		{File: "testdata/psd/example_2.psb", expectedType: parser.PSD, expectedSize: parser.ImageSize{Width: 40000, Height: 32000}},
+		{File: "testdata/psd/example_3.psd", expectedType: parser.PSD, expectedSize: parser.ImageSize{Width: 900, Height: 600}},
+
+		// SVG
+		{File: "testdata/svg/example_1.svg", expectedType: parser.SVG, expectedSize: parser.ImageSize{Width: 794, Height: 1123}},
+		{File: "testdata/svg/example_2.svg", expectedType: parser.SVG, expectedSize: parser.ImageSize{Width: 256, Height: 128}},
+		{File: "testdata/svg/example_3.svg", expectedType: parser.SVG, expectedSize: parser.ImageSize{Width: 64, Height: 48}},
+		{File: "testdata/svg/example_4.svg", expectedType: parser.SVG, expectedSize: parser.ImageSize{Width: 0, Height: 0}},
+
+		// Netpbm
+		{File: "testdata/netpbm/example_1.pbm", expectedType: parser.PBM, expectedSize: parser.ImageSize{Width: 8, Height: 4}},
+		{File: "testdata/netpbm/example_2.pgm", expectedType: parser.PGM, expectedSize: parser.ImageSize{Width: 32, Height: 24}},
+		{File: "testdata/netpbm/example_3.ppm", expectedType: parser.PPM, expectedSize: parser.ImageSize{Width: 64, Height: 48}},
+		{File: "testdata/netpbm/example_4.pam", expectedType: parser.PAM, expectedSize: parser.ImageSize{Width: 227, Height: 149}},
+		{File: "testdata/netpbm/example_5.pfm", expectedType: parser.PFM, expectedSize: parser.ImageSize{Width: 10, Height: 7}},
+
+		// TGA
+		{File: "testdata/tga/example_1.tga", expectedType: parser.TGA, expectedSize: parser.ImageSize{Width: 64, Height: 32}},
+		{File: "testdata/tga/example_2.tga", expectedType: parser.TGA, expectedSize: parser.ImageSize{Width: 40, Height: 30}},
+
+		// QOI
+		{File: "testdata/qoi/example_1.qoi", expectedType: parser.QOI, expectedSize: parser.ImageSize{Width: 320, Height: 200}},
+		{File: "testdata/qoi/example_2.qoi", expectedType: parser.QOI, expectedSize: parser.ImageSize{Width: 17, Height: 9}},
+
+		// DDS
+		{File: "testdata/dds/example_1.dds", expectedType: parser.DDS, expectedSize: parser.ImageSize{Width: 256, Height: 128}},
+		{File: "testdata/dds/example_2.dds", expectedType: parser.DDS, expectedSize: parser.ImageSize{Width: 64, Height: 64}},
+
+		// KTX
+		{File: "testdata/ktx/example_1.ktx", expectedType: parser.KTX, expectedSize: parser.ImageSize{Width: 512, Height: 256}},
+		{File: "testdata/ktx/example_2.ktx2", expectedType: parser.KTX2, expectedSize: parser.ImageSize{Width: 1024, Height: 1024}},
+
+		// EXR
+		{File: "testdata/exr/example_1.exr", expectedType: parser.EXR, expectedSize: parser.ImageSize{Width: 640, Height: 480}},
+
+		// HDR
+		{File: "testdata/hdr/example_1.hdr", expectedType: parser.HDR, expectedSize: parser.ImageSize{Width: 1024, Height: 768}},
+		{File: "testdata/hdr/example_2.hdr", expectedType: parser.HDR, expectedSize: parser.ImageSize{Width: 300, Height: 200}},
+
+		// JPEG 2000
+		{File: "testdata/jpeg2000/example_1.j2k", expectedType: parser.J2K, expectedSize: parser.ImageSize{Width: 1000, Height: 750}},
+		{File: "testdata/jpeg2000/example_2.jp2", expectedType: parser.JP2, expectedSize: parser.ImageSize{Width: 1000, Height: 750}},
+		{File: "testdata/jpeg2000/example_3.jpx", expectedType: parser.JPX, expectedSize: parser.ImageSize{Width: 2048, Height: 1536}},
+
+		// Camera raw
+		{File: "testdata/raw/example_1.dng", expectedType: parser.DNG, expectedSize: parser.ImageSize{Width: 6000, Height: 4000}},
+		{File: "testdata/raw/example_2.cr2", expectedType: parser.CR2, expectedSize: parser.ImageSize{Width: 6880, Height: 4544}},
+		{File: "testdata/raw/example_3.nef", expectedType: parser.NEF, expectedSize: parser.ImageSize{Width: 6048, Height: 4032}},
+		{File: "testdata/raw/example_4.arw", expectedType: parser.ARW, expectedSize: parser.ImageSize{Width: 6048, Height: 4024}},
+		{File: "testdata/raw/example_5.orf", expectedType: parser.ORF, expectedSize: parser.ImageSize{Width: 4640, Height: 3472}},
+		{File: "testdata/raw/example_6.rw2", expectedType: parser.RW2, expectedSize: parser.ImageSize{Width: 5248, Height: 3920}},
+		{File: "testdata/raw/example_7.pef", expectedType: parser.PEF, expectedSize: parser.ImageSize{Width: 6080, Height: 4064}},
+		{File: "testdata/raw/example_8.cr3", expectedType: parser.CR3, expectedSize: parser.ImageSize{Width: 6888, Height: 4546}},
+		{File: "testdata/raw/example_9.raf", expectedType: parser.RAF, expectedSize: parser.ImageSize{Width: 6160, Height: 4032}},
+		{File: "testdata/raw/example_10.cr2", expectedType: parser.CR2, expectedSize: parser.ImageSize{Width: 3516, Height: 2328}},
 	}
 
 	for _, testCase := range testCases {
@@ -93,3 +219,751 @@ func Test(t *testing.T) {
 		GetInfoFromFileTesting(testCase.File, testCase.expectedType, testCase.expectedSize, t)
 	}
 }
+
+func TestOrientation(t *testing.T) {
+	testCases := []struct {
+		File                string
+		expectedOrientation parser.Orientation
+		expectedDisplaySize parser.ImageSize
+	}{
+		{File: "testdata/jpeg/example_1.jpg", expectedOrientation: parser.OrientationNormal, expectedDisplaySize: parser.ImageSize{Width: 2048, Height: 1536}},
+		{File: "testdata/jpeg/example_5.jpg", expectedOrientation: parser.OrientationRotate90, expectedDisplaySize: parser.ImageSize{Width: 480, Height: 640}},
+		{File: "testdata/png/example_4.png", expectedOrientation: parser.OrientationRotate270, expectedDisplaySize: parser.ImageSize{Width: 200, Height: 300}},
+		{File: "testdata/png/example_10.png", expectedOrientation: parser.OrientationRotate90, expectedDisplaySize: parser.ImageSize{Width: 32, Height: 48}},
+		{File: "testdata/webp/example_4.webp", expectedOrientation: parser.OrientationRotate180, expectedDisplaySize: parser.ImageSize{Width: 400, Height: 300}},
+		{File: "testdata/tiff/example_7.tif", expectedOrientation: parser.OrientationTranspose, expectedDisplaySize: parser.ImageSize{Width: 50, Height: 100}},
+		{File: "testdata/heic/example_3.heic", expectedOrientation: parser.OrientationTransverse, expectedDisplaySize: parser.ImageSize{Width: 3024, Height: 4032}},
+	}
+
+	for _, testCase := range testCases {
+		SetChunkSize(1)
+		imageInfo, err := GetInfoFromFile(testCase.File)
+		if err != nil {
+			panic(err)
+		}
+
+		if imageInfo.Orientation != testCase.expectedOrientation || imageInfo.DisplaySize != testCase.expectedDisplaySize {
+			t.Errorf("File %s is expected to have orientation %s and display size %+v, but got %s and %+v.",
+				testCase.File, testCase.expectedOrientation, testCase.expectedDisplaySize,
+				imageInfo.Orientation, imageInfo.DisplaySize)
+		}
+	}
+
+	// The EXIF chunk of WebP files follows the image data, which is skipped if the
+	// reader supports random access
+	SetChunkSize(1)
+	f, err := os.Open("testdata/webp/example_4.webp")
+	if err != nil {
+		panic(err)
+	}
+	defer f.Close()
+
+	imageInfo, length, err := GetInfoFromReader(f)
+	if err != nil {
+		panic(err)
+	}
+
+	if imageInfo.Orientation != parser.OrientationRotate180 || length >= 52 {
+		t.Errorf("File testdata/webp/example_4.webp is expected to have orientation %s before its EXIF chunk at offset 52, but got %s from %d bytes.",
+			parser.OrientationRotate180, imageInfo.Orientation, length)
+	}
+}
+
+func TestPixelFormat(t *testing.T) {
+	testCases := []struct {
+		File                string
+		expectedPixelFormat parser.PixelFormat
+	}{
+		{File: "testdata/jpeg/example_1.jpg", expectedPixelFormat: parser.PixelFormat{BitDepth: 8, Channels: 3, ColorModel: parser.ColorModelYCbCr}},
+		{File: "testdata/jpeg/example_8.jpg", expectedPixelFormat: parser.PixelFormat{BitDepth: 8, Channels: 4, ColorModel: parser.ColorModelCMYK}},
+		{File: "testdata/png/example_2.png", expectedPixelFormat: parser.PixelFormat{BitDepth: 1, Channels: 1, ColorModel: parser.ColorModelPalette}},
+		{File: "testdata/png/example_3.png", expectedPixelFormat: parser.PixelFormat{BitDepth: 8, Channels: 4, ColorModel: parser.ColorModelRGB, Alpha: true}},
+		{File: "testdata/png/example_6.png", expectedPixelFormat: parser.PixelFormat{BitDepth: 16, Channels: 1, ColorModel: parser.ColorModelGray, Alpha: true}},
+		{File: "testdata/gif/example_2.gif", expectedPixelFormat: parser.PixelFormat{BitDepth: 8, Channels: 1, ColorModel: parser.ColorModelPalette}},
+		{File: "testdata/gif/example_4.gif", expectedPixelFormat: parser.PixelFormat{BitDepth: 4, Channels: 1, ColorModel: parser.ColorModelPalette, Alpha: true}},
+		{File: "testdata/bmp/example_1.bmp", expectedPixelFormat: parser.PixelFormat{BitDepth: 8, Channels: 1, ColorModel: parser.ColorModelPalette}},
+		{File: "testdata/bmp/example_3.bmp", expectedPixelFormat: parser.PixelFormat{BitDepth: 8, Channels: 4, ColorModel: parser.ColorModelRGB, Alpha: true}},
+		{File: "testdata/webp/example_1.webp", expectedPixelFormat: parser.PixelFormat{BitDepth: 8, Channels: 3, ColorModel: parser.ColorModelYCbCr}},
+		{File: "testdata/webp/example_2.webp", expectedPixelFormat: parser.PixelFormat{BitDepth: 8, Channels: 4, ColorModel: parser.ColorModelRGB, Alpha: true}},
+		{File: "testdata/webp/example_3.webp", expectedPixelFormat: parser.PixelFormat{BitDepth: 8, Channels: 4, ColorModel: parser.ColorModelYCbCr, Alpha: true}},
+		{File: "testdata/tiff/example_4.tif", expectedPixelFormat: parser.PixelFormat{BitDepth: 8, Channels: 3, ColorModel: parser.ColorModelRGB}},
+		{File: "testdata/tiff/example_9.tif", expectedPixelFormat: parser.PixelFormat{BitDepth: 16, Channels: 4, ColorModel: parser.ColorModelRGB, Alpha: true}},
+		{File: "testdata/avif/example_3.avif", expectedPixelFormat: parser.PixelFormat{BitDepth: 10, Channels: 4, ColorModel: parser.ColorModelYCbCr, Alpha: true}},
+		{File: "testdata/jxl/example_4.jxl", expectedPixelFormat: parser.PixelFormat{BitDepth: 16, Channels: 2, ColorModel: parser.ColorModelGray, Alpha: true}},
+		{File: "testdata/psd/example_2.psb", expectedPixelFormat: parser.PixelFormat{BitDepth: 16, Channels: 4, ColorModel: parser.ColorModelCMYK}},
+		{File: "testdata/netpbm/example_4.pam", expectedPixelFormat: parser.PixelFormat{BitDepth: 8, Channels: 4, ColorModel: parser.ColorModelRGB, Alpha: true}},
+		{File: "testdata/exr/example_1.exr", expectedPixelFormat: parser.PixelFormat{BitDepth: 16, Channels: 3, ColorModel: parser.ColorModelRGB}},
+		{File: "testdata/tga/example_2.tga", expectedPixelFormat: parser.PixelFormat{BitDepth: 8, Channels: 4, ColorModel: parser.ColorModelRGB, Alpha: true}},
+		{File: "testdata/qoi/example_2.qoi", expectedPixelFormat: parser.PixelFormat{BitDepth: 8, Channels: 3, ColorModel: parser.ColorModelRGB}},
+		{File: "testdata/svg/example_1.svg", expectedPixelFormat: parser.PixelFormat{}},
+	}
+
+	for _, testCase := range testCases {
+		SetChunkSize(1)
+		imageInfo, err := GetInfoFromFile(testCase.File)
+		if err != nil {
+			panic(err)
+		}
+
+		if imageInfo.PixelFormat != testCase.expectedPixelFormat {
+			t.Errorf("File %s is expected to have pixel format %+v, but got %+v.",
+				testCase.File, testCase.expectedPixelFormat, imageInfo.PixelFormat)
+		}
+	}
+}
+
+func TestResolution(t *testing.T) {
+	testCases := []struct {
+		File               string
+		expectedResolution parser.Resolution
+	}{
+		{File: "testdata/jpeg/example_1.jpg", expectedResolution: parser.Resolution{X: 72, Y: 72, Unit: parser.ResolutionUnitInch}},
+		{File: "testdata/jpeg/example_2.jpg", expectedResolution: parser.Resolution{X: 180, Y: 180, Unit: parser.ResolutionUnitInch}},
+		{File: "testdata/jpeg/example_9.jpg", expectedResolution: parser.Resolution{X: 118.11, Y: 118.11, Unit: parser.ResolutionUnitCentimeter}},
+		{File: "testdata/jpeg/example_11.jpg", expectedResolution: parser.Resolution{X: 2, Y: 1, Unit: parser.ResolutionUnitNone}},
+		{File: "testdata/png/example_2.png", expectedResolution: parser.Resolution{X: 28.34, Y: 28.34, Unit: parser.ResolutionUnitCentimeter}},
+		{File: "testdata/png/example_1.png", expectedResolution: parser.Resolution{}},
+		{File: "testdata/bmp/example_3.bmp", expectedResolution: parser.Resolution{X: 28.35, Y: 28.35, Unit: parser.ResolutionUnitCentimeter}},
+		{File: "testdata/tiff/example_6.tif", expectedResolution: parser.Resolution{X: 300, Y: 300, Unit: parser.ResolutionUnitInch}},
+		{File: "testdata/psd/example_3.psd", expectedResolution: parser.Resolution{X: 300, Y: 300, Unit: parser.ResolutionUnitInch}},
+		{File: "testdata/psd/example_1.psd", expectedResolution: parser.Resolution{}},
+		{File: "testdata/heic/example_4.heic", expectedResolution: parser.Resolution{X: 240, Y: 240, Unit: parser.ResolutionUnitInch}},
+	}
+
+	for _, testCase := range testCases {
+		SetChunkSize(1)
+		imageInfo, err := GetInfoFromFile(testCase.File)
+		if err != nil {
+			panic(err)
+		}
+
+		if imageInfo.Resolution != testCase.expectedResolution {
+			t.Errorf("File %s is expected to have resolution %+v, but got %+v.",
+				testCase.File, testCase.expectedResolution, imageInfo.Resolution)
+		}
+	}
+
+	// The Exif item of HEIF files follows the image data. The resolution must not
+	// depend on whether the reader supports random access or on the chunk size.
+	data, err := ioutil.ReadFile("testdata/heic/example_4.heic")
+	if err != nil {
+		panic(err)
+	}
+
+	result, imageInfo, err := GetInfo(data)
+	expectedResolution := parser.Resolution{X: 240, Y: 240, Unit: parser.ResolutionUnitInch}
+	if err != nil || result != Valid || imageInfo.Resolution != expectedResolution {
+		t.Errorf("File testdata/heic/example_4.heic is expected to have resolution %+v, but got %+v.",
+			expectedResolution, imageInfo.Resolution)
+	}
+
+	SetChunkSize(1)
+	imageInfo, _, err = GetInfoFromReader(struct{ io.Reader }{strings.NewReader(string(data))})
+	if err != nil || imageInfo.Resolution != expectedResolution {
+		t.Errorf("File testdata/heic/example_4.heic is expected to have resolution %+v without random access, but got %+v.",
+			expectedResolution, imageInfo.Resolution)
+	}
+
+	x, y := parser.Resolution{X: 118.11, Y: 118.11, Unit: parser.ResolutionUnitCentimeter}.DPI()
+	if math.Abs(x-300) > 0.01 || math.Abs(y-300) > 0.01 {
+		t.Errorf("Resolution is expected to be 300 DPI, but got %f x %f.", x, y)
+	}
+}
+
+func TestAnimation(t *testing.T) {
+	testCases := []struct {
+		File              string
+		maxFrames         int
+		expectedAnimation parser.Animation
+	}{
+		{File: "testdata/gif/example_5.gif", expectedAnimation: parser.Animation{Animated: true, Frames: 3, Loops: 0, Duration: 600 * time.Millisecond}},
+		{File: "testdata/gif/example_5.gif", maxFrames: 2, expectedAnimation: parser.Animation{Animated: true, Frames: 2, Loops: 0, Duration: 300 * time.Millisecond, Truncated: true}},
+		{File: "testdata/gif/example_5.gif", maxFrames: 3, expectedAnimation: parser.Animation{Animated: true, Frames: 3, Loops: 0, Duration: 600 * time.Millisecond}},
+		{File: "testdata/gif/example_5.gif", maxFrames: 1, expectedAnimation: parser.Animation{Animated: true, Frames: 1, Loops: 0, Duration: 100 * time.Millisecond, Truncated: true}},
+		{File: "testdata/gif/example_6.gif", expectedAnimation: parser.Animation{Animated: true, Frames: 2, Loops: 2, Duration: time.Second}},
+		{File: "testdata/gif/example_1.gif", expectedAnimation: parser.Animation{Frames: 1}},
+		{File: "testdata/png/example_7.png", expectedAnimation: parser.Animation{Animated: true, Frames: 2, Loops: 3, Duration: 750 * time.Millisecond}},
+		{File: "testdata/png/example_7.png", maxFrames: 2, expectedAnimation: parser.Animation{Animated: true, Frames: 2, Loops: 3, Duration: 750 * time.Millisecond}},
+		{File: "testdata/png/example_1.png", expectedAnimation: parser.Animation{Frames: 1}},
+		{File: "testdata/webp/example_6.webp", expectedAnimation: parser.Animation{Animated: true, Frames: 3, Loops: 2, Duration: 300 * time.Millisecond}},
+		{File: "testdata/webp/example_6.webp", maxFrames: 3, expectedAnimation: parser.Animation{Animated: true, Frames: 3, Loops: 2, Duration: 300 * time.Millisecond}},
+		{File: "testdata/webp/example_1.webp", expectedAnimation: parser.Animation{Frames: 1}},
+		{File: "testdata/avif/example_4.avif", expectedAnimation: parser.Animation{Animated: true, Frames: 5, Loops: 0, Duration: 200 * time.Millisecond}},
+		{File: "testdata/avif/example_4.avif", maxFrames: 3, expectedAnimation: parser.Animation{Animated: true, Frames: 3, Loops: 0, Duration: 120 * time.Millisecond, Truncated: true}},
+		{File: "testdata/avif/example_4.avif", maxFrames: 5, expectedAnimation: parser.Animation{Animated: true, Frames: 5, Loops: 0, Duration: 200 * time.Millisecond}},
+		{File: "testdata/avif/example_1.avif", expectedAnimation: parser.Animation{Frames: 1}},
+		{File: "testdata/jpeg/example_1.jpg", expectedAnimation: parser.Animation{}},
+	}
+
+	for _, testCase := range testCases {
+		SetChunkSize(1)
+		animation, err := GetAnimationFromFile(testCase.File, testCase.maxFrames)
+		if err != nil {
+			panic(err)
+		}
+
+		if animation != testCase.expectedAnimation {
+			t.Errorf("File %s is expected to have animation %+v, but got %+v.",
+				testCase.File, testCase.expectedAnimation, animation)
+		}
+	}
+}
+
+func TestColorProfile(t *testing.T) {
+	testCases := []struct {
+		File                string
+		expectedICC         bool
+		expectedDescription string
+		expectedColorSpace  string
+	}{
+		{File: "testdata/jpeg/example_10.jpg", expectedICC: true, expectedDescription: "Display P3", expectedColorSpace: "RGB"},
+		{File: "testdata/jpeg/example_1.jpg"},
+		{File: "testdata/png/example_8.png", expectedICC: true, expectedDescription: "Adobe RGB (1998)", expectedColorSpace: "RGB"},
+		{File: "testdata/png/example_1.png"},
+		{File: "testdata/webp/example_7.webp", expectedICC: true, expectedDescription: "sRGB IEC61966-2.1", expectedColorSpace: "RGB"},
+		{File: "testdata/tiff/example_10.tif", expectedICC: true, expectedDescription: "Dot Gain 20%", expectedColorSpace: "GRAY"},
+		{File: "testdata/avif/example_5.avif", expectedICC: true, expectedDescription: "Display P3", expectedColorSpace: "RGB"},
+	}
+
+	for _, testCase := range testCases {
+		SetChunkSize(1)
+		colorProfile, err := GetColorProfileFromFile(testCase.File)
+		if err != nil {
+			panic(err)
+		}
+
+		if (colorProfile.ICC != nil) != testCase.expectedICC || colorProfile.Description != testCase.expectedDescription ||
+			colorProfile.ColorSpace != testCase.expectedColorSpace {
+			t.Errorf("File %s is expected to have profile %q (%s), but got %q (%s).",
+				testCase.File, testCase.expectedDescription, testCase.expectedColorSpace,
+				colorProfile.Description, colorProfile.ColorSpace)
+		}
+	}
+
+	SetChunkSize(1)
+	colorProfile, err := GetColorProfileFromFile("testdata/png/example_9.png")
+	if err != nil {
+		panic(err)
+	}
+
+	expectedCICP := parser.CICP{ColorPrimaries: 1, TransferCharacteristics: 13, FullRange: true}
+	if !colorProfile.SRGB || colorProfile.Gamma != 0.45455 || !colorProfile.HasCICP || colorProfile.CICP != expectedCICP {
+		t.Errorf("File testdata/png/example_9.png has unexpected color information %+v.", colorProfile)
+	}
+
+	if !colorProfile.HasChromaticities || colorProfile.WhitePoint != (parser.Chromaticity{X: 0.3127, Y: 0.329}) ||
+		colorProfile.Blue != (parser.Chromaticity{X: 0.15, Y: 0.06}) {
+		t.Errorf("File testdata/png/example_9.png has unexpected chromaticities %+v.", colorProfile)
+	}
+
+	colorProfile, err = GetColorProfileFromFile("testdata/avif/example_5.avif")
+	if err != nil {
+		panic(err)
+	}
+
+	expectedCICP = parser.CICP{ColorPrimaries: 12, TransferCharacteristics: 13, MatrixCoefficients: 6, FullRange: true}
+	if !colorProfile.HasCICP || colorProfile.CICP != expectedCICP {
+		t.Errorf("File testdata/avif/example_5.avif is expected to have code points %+v, but got %+v.",
+			expectedCICP, colorProfile.CICP)
+	}
+
+	// Segment chain broken by a wrong segment length in front of the ICC segments
+	data, err := ioutil.ReadFile("testdata/jpeg/example_10.jpg")
+	if err != nil {
+		panic(err)
+	}
+
+	broken := append([]byte{}, data[:4]...)
+	broken = append(broken, 0x00, 0x01)
+	result, _, err := GetColorProfile(append(broken, data[6:]...))
+	if err != nil || result != Invalid {
+		t.Errorf("Expected result %s for a broken segment chain, but got %s.", Invalid, result)
+	}
+}
+
+func TestEXIF(t *testing.T) {
+	SetChunkSize(64)
+	exif, err := GetEXIFFromFile("testdata/jpeg/example_6.jpg", parser.EXIFAll)
+	if err != nil {
+		panic(err)
+	}
+
+	if exif.Make != "FUJIFILM" || exif.Model != "X-T3" || exif.Orientation != parser.OrientationRotate270 {
+		t.Errorf("Expected a FUJIFILM X-T3 with orientation Rotate270, but got %+v.", exif)
+	}
+
+	expectedTime := time.Date(2021, 6, 12, 12, 3, 27, 0, time.UTC)
+	if !exif.DateTimeOriginal.Equal(expectedTime) {
+		t.Errorf("Expected capture time %s, but got %s.", expectedTime, exif.DateTimeOriginal)
+	}
+
+	if exif.ExposureTime != (parser.TIFFRational{Numerator: 1, Denominator: 250}) || exif.FNumber != 5.6 || exif.ISO != 400 ||
+		exif.FocalLength != 35 || exif.FocalLengthIn35mmFilm != 52 || exif.LensModel != "XF35mmF1.4 R" {
+		t.Errorf("Expected exposure 1/250 at f/5.6, ISO 400 and a 35mm lens, but got %+v.", exif)
+	}
+
+	if !exif.HasGPS || math.Abs(exif.Latitude-48.1429333) > 1e-6 || math.Abs(exif.Longitude+11.57) > 1e-6 || exif.Altitude != -12.5 {
+		t.Errorf("Expected GPS coordinates 48.1429333, -11.57 at -12.5m, but got %+v.", exif)
+	}
+
+	if exif.InteropIndex != "R98" {
+		t.Errorf("Expected interoperability index R98, but got %q.", exif.InteropIndex)
+	}
+
+	// The thumbnail after the IFDs is not needed
+	f, err := os.Open("testdata/jpeg/example_6.jpg")
+	if err != nil {
+		panic(err)
+	}
+	defer f.Close()
+
+	exif, count, err := GetEXIFFromReader(f, parser.EXIFCamera)
+	if err != nil {
+		panic(err)
+	}
+
+	if exif.Model != "X-T3" || count > 1024 {
+		t.Errorf("Expected model X-T3 after reading at most 1024 bytes, but got %q after %d bytes.", exif.Model, count)
+	}
+}
+
+func TestXMP(t *testing.T) {
+	files := []string{
+		"testdata/png/example_5.png",
+		"testdata/png/example_10.png",
+		"testdata/gif/example_3.gif",
+		"testdata/webp/example_5.webp",
+		"testdata/tiff/example_8.tif",
+	}
+
+	for _, file := range files {
+		SetChunkSize(64)
+		packet, err := GetXMPFromFile(file)
+		if err != nil {
+			panic(err)
+		}
+
+		xmp, err := parser.ParseXMP(packet)
+		if err != nil {
+			t.Fatalf("File %s is expected to contain a valid XMP packet, but got %s.", file, err)
+		}
+
+		if xmp.Rating != 5 || len(xmp.Subject) != 1 || xmp.Subject[0] != "sunset" {
+			t.Errorf("File %s is expected to have rating 5 and keyword sunset, but got %+v.", file, xmp)
+		}
+	}
+
+	data, err := ioutil.ReadFile("testdata/jpeg/example_7.jpg")
+	if err != nil {
+		panic(err)
+	}
+
+	result, packet, err := GetXMP(data)
+	if err != nil || result != Valid {
+		t.Fatalf("Expected result %s, but got %s.", Valid, result)
+	}
+
+	xmp, err := parser.ParseXMP(packet)
+	if err != nil {
+		t.Fatalf("Expected a valid XMP packet, but got %s.", err)
+	}
+
+	if xmp.Title != "Morning fog" || xmp.Rating != 4 || xmp.Label != "Green" || xmp.Rights != "(c) 2021 Jane Doe" ||
+		len(xmp.Creator) != 1 || len(xmp.Subject) != 3 || xmp.CreateDate != "2021-06-12T14:03:27+02:00" {
+		t.Errorf("Expected the properties of the test packet, but got %+v.", xmp)
+	}
+
+	SetChunkSize(64)
+	extended, err := GetExtendedXMPFromFile("testdata/jpeg/example_7.jpg")
+	if err != nil {
+		panic(err)
+	}
+
+	if len(extended) < 100000 || !strings.Contains(string(extended), "crs:Exposure2012") {
+		t.Errorf("Expected the reassembled extended XMP packet, but got %d bytes.", len(extended))
+	}
+
+	extended, err = GetExtendedXMPFromFile("testdata/png/example_5.png")
+	if err != nil || extended != nil {
+		t.Errorf("Expected no extended XMP packet for a PNG file, but got %d bytes.", len(extended))
+	}
+}
+
+func TestPNGMetadataAfterImageData(t *testing.T) {
+	// The eXIf and iTXt chunks of this file follow the image data at offset 33
+	data, err := ioutil.ReadFile("testdata/png/example_10.png")
+	if err != nil {
+		panic(err)
+	}
+
+	result, exif, err := GetEXIF(data, parser.EXIFCamera)
+	if err != nil || result != Valid {
+		t.Fatalf("Expected result %s, but got %s.", Valid, result)
+	}
+
+	if exif.Make != "Camera" || exif.Orientation != parser.OrientationRotate90 {
+		t.Errorf("Expected make Camera with orientation Rotate90, but got %+v.", exif)
+	}
+
+	// Without random access the whole file is read
+	SetChunkSize(64)
+	imageInfo, _, err := GetInfoFromReader(struct{ io.Reader }{strings.NewReader(string(data))})
+	if err != nil {
+		panic(err)
+	}
+
+	if imageInfo.Orientation != parser.OrientationRotate90 {
+		t.Errorf("Expected orientation %s, but got %s.", parser.OrientationRotate90, imageInfo.Orientation)
+	}
+
+	// With random access the image data is skipped
+	SetChunkSize(1)
+	imageInfo, length, err := GetInfoFromReader(strings.NewReader(string(data)))
+	if err != nil {
+		panic(err)
+	}
+
+	if imageInfo.Orientation != parser.OrientationRotate90 || length > 64 {
+		t.Errorf("Expected orientation %s from at most 64 bytes, but got %s from %d bytes.",
+			parser.OrientationRotate90, imageInfo.Orientation, length)
+	}
+}
+
+func TestTIFFTrailingIFD(t *testing.T) {
+	// The IFD of this file is stored at its end
+	data, err := ioutil.ReadFile("testdata/tiff/example_1.tif")
+	if err != nil {
+		panic(err)
+	}
+
+	if result := (parser.TIFFParser{}).DetectType(data[:4]); result != parser.Valid {
+		t.Errorf("Expected result %s from the magic number, but got %s.", parser.Valid, result)
+	}
+
+	SetChunkSize(1)
+	f, err := os.Open("testdata/tiff/example_1.tif")
+	if err != nil {
+		panic(err)
+	}
+	defer f.Close()
+
+	imageType, length, err := DetectTypeFromReader(f)
+	if err != nil {
+		panic(err)
+	}
+
+	if imageType != parser.TIFF || length > 16 {
+		t.Errorf("Expected type %s from the header, but got %s from %d bytes.", parser.TIFF, imageType, length)
+	}
+}
+
+func TestTIFFPages(t *testing.T) {
+	data, err := ioutil.ReadFile("testdata/tiff/example_5.tif")
+	if err != nil {
+		panic(err)
+	}
+
+	result, pages := parser.TIFFParser{}.GetPages(data)
+	if result != parser.Valid {
+		t.Fatalf("Expected result %s, but got %s.", parser.Valid, result)
+	}
+
+	if len(pages) != 3 {
+		t.Fatalf("Expected 3 pages, but got %d.", len(pages))
+	}
+
+	if pages[0].Size != (parser.ImageSize{Width: 1728, Height: 2200}) || pages[0].Compression != 4 || pages[0].Reduced() {
+		t.Errorf("Expected a full resolution fax page, but got %+v.", pages[0])
+	}
+
+	if !pages[1].Reduced() || !pages[1].SubIFD || len(pages[1].BitsPerSample) != 3 || pages[1].PhotometricInterpretation != 2 {
+		t.Errorf("Expected a RGB thumbnail in a SubIFD, but got %+v.", pages[1])
+	}
+
+	if pages[2].Size != (parser.ImageSize{Width: 1728, Height: 1100}) || pages[2].SubIFD || pages[2].BitsPerSample[0] != 1 {
+		t.Errorf("Expected the second fax page, but got %+v.", pages[2])
+	}
+}
+
+func TestTIFFIFDs(t *testing.T) {
+	data, err := ioutil.ReadFile("testdata/tiff/example_6.tif")
+	if err != nil {
+		panic(err)
+	}
+
+	result, _ := parser.TIFFParser{}.GetIFDs(data[:200])
+	if result != parser.NeedMoreData {
+		t.Errorf("Expected result %s for truncated values, but got %s.", parser.NeedMoreData, result)
+	}
+
+	result, ifds := parser.TIFFParser{}.GetIFDs(data)
+	if result != parser.Valid || len(ifds) != 1 {
+		t.Fatalf("Expected a single IFD, but got %d (%s).", len(ifds), result)
+	}
+
+	tags := ifds[0].Tags
+
+	if resolution, ok := tags[282].Float(); !ok || resolution != 300 {
+		t.Errorf("Expected a resolution of 300, but got %+v.", tags[282])
+	}
+
+	if tags[305].String != "Scanner Suite 4.2" {
+		t.Errorf("Expected software Scanner Suite 4.2, but got %q.", tags[305].String)
+	}
+
+	if len(tags[318].Rationals) != 2 || tags[318].Rationals[1] != (parser.TIFFRational{Numerator: 329, Denominator: 1000}) {
+		t.Errorf("Expected two white point rationals, but got %+v.", tags[318])
+	}
+
+	if len(tags[33550].Floats) != 3 || tags[33550].Floats[0] != 0.25 {
+		t.Errorf("Expected three doubles, but got %+v.", tags[33550])
+	}
+
+	if len(tags[50000].Ints) != 2 || tags[50000].Ints[0] != -5 || tags[50003].Ints[2] != -3 {
+		t.Errorf("Expected signed values, but got %+v and %+v.", tags[50000], tags[50003])
+	}
+
+	if tags[50001].Rationals[0].Numerator != -1 || tags[50002].Floats[0] != 1.5 || len(tags[50004].Bytes) != 6 {
+		t.Errorf("Expected SRATIONAL, FLOAT and UNDEFINED values, but got %+v, %+v and %+v.", tags[50001], tags[50002], tags[50004])
+	}
+}
+
+func TestICOEntries(t *testing.T) {
+	data, err := ioutil.ReadFile("testdata/ico/example_2.ico")
+	if err != nil {
+		panic(err)
+	}
+
+	result, entries := parser.ICOParser{}.GetEntries(data)
+	if result != parser.Valid {
+		t.Fatalf("Expected result %s, but got %s.", parser.Valid, result)
+	}
+
+	if len(entries) != 2 {
+		t.Fatalf("Expected 2 entries, but got %d.", len(entries))
+	}
+
+	if entries[0].PNG || entries[0].Size.Width != 16 || entries[0].Size.Height != 16 {
+		t.Errorf("Expected first entry to be a 16x16 bitmap, but got %+v.", entries[0])
+	}
+
+	if !entries[1].PNG || entries[1].Size.Width != 256 || entries[1].PNGSize.Width != 512 || entries[1].PNGSize.Height != 512 {
+		t.Errorf("Expected second entry to be a 512x512 png with a directory size of 256, but got %+v.", entries[1])
+	}
+}
+
+func TestPSDHeader(t *testing.T) {
+	data, err := ioutil.ReadFile("testdata/psd/example_2.psb")
+	if err != nil {
+		panic(err)
+	}
+
+	result, header := parser.PSDParser{}.GetHeader(data)
+	if result != parser.Valid {
+		t.Fatalf("Expected result %s, but got %s.", parser.Valid, result)
+	}
+
+	if header.Version != 2 || header.Channels != 4 || header.Depth != 16 || header.ColorMode != parser.PSDCMYK {
+		t.Errorf("Expected a 16 bit CMYK PSB header with 4 channels, but got %+v.", header)
+	}
+}
+
+func TestTGAFooter(t *testing.T) {
+	// This file is only detected by its footer
+	data, err := ioutil.ReadFile("testdata/tga/example_2.tga")
+	if err != nil {
+		panic(err)
+	}
+
+	// The image starts at the current position of the reader, not at its start
+	r := strings.NewReader(strings.Repeat("\x00", 100) + string(data))
+	if _, err := r.Seek(100, 0); err != nil {
+		panic(err)
+	}
+
+	SetChunkSize(64)
+	imageInfo, _, err := GetInfoFromReader(r)
+	if err != nil {
+		panic(err)
+	}
+
+	if imageInfo.Type != parser.TGA || imageInfo.Size != (parser.ImageSize{Width: 40, Height: 30}) {
+		t.Errorf("Expected a TGA image of size 40x30, but got %s of size %+v.", imageInfo.Type, imageInfo.Size)
+	}
+}
+
+func TestSVGRelativeSize(t *testing.T) {
+	tests := []struct {
+		File               string
+		expectedSize       parser.ImageSize
+		expectedHasViewBox bool
+	}{
+		{File: "testdata/svg/example_3.svg", expectedSize: parser.ImageSize{Width: 64, Height: 48}, expectedHasViewBox: true},
+		{File: "testdata/svg/example_4.svg", expectedSize: parser.ImageSize{}, expectedHasViewBox: false},
+	}
+
+	for _, test := range tests {
+		data, err := ioutil.ReadFile(test.File)
+		if err != nil {
+			panic(err)
+		}
+
+		result, size := parser.SVGParser{}.GetSVGSize(data)
+		if result != parser.Valid {
+			t.Fatalf("File %s is expected to have result %s, but got %s.", test.File, parser.Valid, result)
+		}
+
+		if !size.Relative || size.HasViewBox != test.expectedHasViewBox || size.Size != test.expectedSize {
+			t.Errorf("File %s is expected to have a relative size of %+v, but got %+v.", test.File, test.expectedSize, size)
+		}
+	}
+}
+
+func TestPAMHeader(t *testing.T) {
+	data, err := ioutil.ReadFile("testdata/netpbm/example_4.pam")
+	if err != nil {
+		panic(err)
+	}
+
+	result, header := parser.PAMParser{}.GetHeader(data)
+	if result != parser.Valid {
+		t.Fatalf("Expected result %s, but got %s.", parser.Valid, result)
+	}
+
+	if header.Depth != 4 || header.MaxVal != 255 || header.TupleType != "RGB_ALPHA" {
+		t.Errorf("Expected an 8 bit RGB_ALPHA header with depth 4, but got %+v.", header)
+	}
+}
+
+func TestTextureInfo(t *testing.T) {
+	testCases := []struct {
+		File                string
+		expectedTextureInfo parser.TextureInfo
+	}{
+		{File: "testdata/dds/example_1.dds", expectedTextureInfo: parser.TextureInfo{Size: parser.ImageSize{Width: 256, Height: 128}, Depth: 1, ArrayLayers: 1, Faces: 1, MipLevels: 9, FourCC: "DXT1"}},
+		{File: "testdata/dds/example_2.dds", expectedTextureInfo: parser.TextureInfo{Size: parser.ImageSize{Width: 64, Height: 64}, Depth: 1, ArrayLayers: 2, Faces: 6, MipLevels: 7, FourCC: "DX10", DXGIFormat: 98}},
+		{File: "testdata/ktx/example_1.ktx", expectedTextureInfo: parser.TextureInfo{Size: parser.ImageSize{Width: 512, Height: 256}, Depth: 1, ArrayLayers: 1, Faces: 1, MipLevels: 10, GLInternalFormat: 0x8D64}},
+		{File: "testdata/ktx/example_2.ktx2", expectedTextureInfo: parser.TextureInfo{Size: parser.ImageSize{Width: 1024, Height: 1024}, Depth: 1, ArrayLayers: 1, Faces: 6, MipLevels: 11, VkFormat: 43}},
+	}
+
+	for _, testCase := range testCases {
+		data, err := ioutil.ReadFile(testCase.File)
+		if err != nil {
+			panic(err)
+		}
+
+		_, imageType, _ := DetectType(data)
+		textureParser, ok := parser.ImageParsers[imageType].(parser.TextureParser)
+		if !ok {
+			t.Errorf("File %s is expected to be a texture, but detected type is %s.", testCase.File, imageType)
+			continue
+		}
+
+		result, textureInfo := textureParser.GetTextureInfo(data)
+		if result != parser.Valid || textureInfo != testCase.expectedTextureInfo {
+			t.Errorf("File %s is expected to have texture info %+v, but got %+v (%s).",
+				testCase.File, testCase.expectedTextureInfo, textureInfo, result)
+		}
+	}
+}
+
+func TestEXRWindows(t *testing.T) {
+	data, err := ioutil.ReadFile("testdata/exr/example_1.exr")
+	if err != nil {
+		panic(err)
+	}
+
+	result, windows := parser.EXRParser{}.GetWindows(data)
+	if result != parser.Valid {
+		t.Fatalf("Expected result %s, but got %s.", parser.Valid, result)
+	}
+
+	displaySize := windows.DisplayWindow.Size()
+	if windows.DataWindow.XMin != 100 || windows.DataWindow.YMin != 50 || displaySize.Width != 1920 || displaySize.Height != 1080 {
+		t.Errorf("Expected a data window starting at (100,50) and a 1920x1080 display window, but got %+v.", windows)
+	}
+}
+
+func TestHDROrientation(t *testing.T) {
+	data, err := ioutil.ReadFile("testdata/hdr/example_2.hdr")
+	if err != nil {
+		panic(err)
+	}
+
+	result, header := parser.HDRParser{}.GetHeader(data)
+	if result != parser.Valid {
+		t.Fatalf("Expected result %s, but got %s.", parser.Valid, result)
+	}
+
+	if header.Orientation != "+X -Y" || header.Format != "32-bit_rle_xyze" {
+		t.Errorf("Expected orientation +X -Y with format 32-bit_rle_xyze, but got %+v.", header)
+	}
+}
+
+func TestJPEG2000Header(t *testing.T) {
+	data, err := ioutil.ReadFile("testdata/jpeg2000/example_1.j2k")
+	if err != nil {
+		panic(err)
+	}
+
+	result, header := parser.J2KParser{}.GetHeader(data)
+	if result != parser.Valid || header.Components != 3 || header.BitDepth != 8 || header.Signed {
+		t.Errorf("Expected an unsigned 8 bit header with 3 components, but got %+v (%s).", header, result)
+	}
+
+	data, err = ioutil.ReadFile("testdata/jpeg2000/example_3.jpx")
+	if err != nil {
+		panic(err)
+	}
+
+	result, header = parser.JPXParser{}.GetHeader(data)
+	if result != parser.Valid || header.Components != 1 || header.BitDepth != 12 || !header.Signed {
+		t.Errorf("Expected a signed 12 bit header with 1 component, but got %+v (%s).", header, result)
+	}
+}
+
+func TestRAWSizes(t *testing.T) {
+	data, err := ioutil.ReadFile("testdata/raw/example_8.cr3")
+	if err != nil {
+		panic(err)
+	}
+
+	result, sizes := parser.CR3Parser{}.GetSizes(data)
+	if result != parser.Valid || sizes.Preview != (parser.ImageSize{Width: 1620, Height: 1080}) {
+		t.Errorf("Expected a preview size of 1620x1080, but got %+v (%s).", sizes, result)
+	}
+
+	data, err = ioutil.ReadFile("testdata/raw/example_9.raf")
+	if err != nil {
+		panic(err)
+	}
+
+	result, sizes = parser.RAFParser{}.GetSizes(data)
+	if result != parser.Valid || sizes.Preview != (parser.ImageSize{Width: 1920, Height: 1280}) {
+		t.Errorf("Expected a preview size of 1920x1280, but got %+v (%s).", sizes, result)
+	}
+
+	// The CFA header is read directly, without the jpeg preview in front of it
+	SetChunkSize(1)
+	f, err := os.Open("testdata/raw/example_9.raf")
+	if err != nil {
+		panic(err)
+	}
+	defer f.Close()
+
+	imageSize, length, err := GetSizeFromReader(f)
+	if err != nil {
+		panic(err)
+	}
+
+	if imageSize != (parser.ImageSize{Width: 6160, Height: 4032}) || length > 108 {
+		t.Errorf("Expected a sensor size of 6160x4032 from the first 108 bytes, but got %+v from %d bytes.", imageSize, length)
+	}
+
+	// A CFA header length far beyond the usual size is not read
+	data[96], data[97], data[98], data[99] = 0x7f, 0xff, 0xff, 0xff
+	result, _ = parser.RAFParser{}.GetInfoFromReaderAt(data, strings.NewReader(string(data)), 1<<40)
+	if result != parser.Invalid {
+		t.Errorf("Expected result %s for a CFA header length of 2 GiB, but got %s.", parser.Invalid, result)
+	}
+}
@@ -0,0 +1,45 @@
+package parser
+
+import (
+	"time"
+)
+
+type Animation struct {
+	// File holds an animation as declared by its header, GIF files are animated if
+	// more than one image is found
+	Animated bool
+
+	// Number of frames, counted up to the frame limit
+	Frames int
+
+	// Number of times the animation is played, 0 means forever
+	Loops int
+
+	// Total duration of the counted frames
+	Duration time.Duration
+
+	// Counting stopped at the frame limit because a further frame was found
+	Truncated bool
+}
+
+// Implemented by parsers of image formats which can store animations. Frames are
+// counted until maxFrames is reached, 0 means no limit. Still images are reported as
+// a single frame.
+type AnimationParser interface {
+	GetAnimation(p []byte, maxFrames int) (Result, Animation)
+}
+
+// animationAddFrame counts a frame with the given duration. If the frame limit has
+// already been reached, the frame is not counted and the animation is marked as
+// truncated. True is returned in this case to stop counting.
+func animationAddFrame(animation *Animation, duration time.Duration, maxFrames int) bool {
+	if maxFrames > 0 && animation.Frames >= maxFrames {
+		animation.Truncated = true
+		return true
+	}
+
+	animation.Frames++
+	animation.Duration += duration
+
+	return false
+}
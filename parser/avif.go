@@ -0,0 +1,103 @@
+package parser
+
+import "io"
+
+// https://aomediacodec.github.io/av1-avif/
+
+type AVIFParser struct{}
+
+func (A AVIFParser) Type() ImageType {
+	return AVIF
+}
+
+func (A AVIFParser) DetectType(p []byte) (r Result) {
+	result, imageType := isobmffImageType(p)
+	if result != Valid {
+		return result
+	}
+
+	if imageType != AVIF {
+		return Invalid
+	}
+
+	return Valid
+}
+
+func (A AVIFParser) GetSize(p []byte) (r Result, t ImageSize) {
+	if result := A.DetectType(p); result != Valid {
+		return result, ImageSize{}
+	}
+
+	return heifGetSize(p)
+}
+
+// GetOrientation combines the rotation and mirror properties of the primary item.
+func (A AVIFParser) GetOrientation(p []byte) (r Result, o Orientation) {
+	if result := A.DetectType(p); result != Valid {
+		return result, OrientationNormal
+	}
+
+	return heifGetOrientation(p)
+}
+
+// GetEXIF decodes the requested fields of the Exif item.
+func (A AVIFParser) GetEXIF(p []byte, fields EXIFFields) (r Result, e EXIF) {
+	if result := A.DetectType(p); result != Valid {
+		return result, EXIF{}
+	}
+
+	return heifGetEXIF(p, fields)
+}
+
+// GetPixelFormat reads the pixel information and the AV1 configuration of the
+// primary item.
+func (A AVIFParser) GetPixelFormat(p []byte) (r Result, f PixelFormat) {
+	if result := A.DetectType(p); result != Valid {
+		return result, PixelFormat{}
+	}
+
+	return heifGetPixelFormat(p)
+}
+
+// GetResolution reads the resolution tags of the EXIF data. The Exif item might be
+// stored after the coded image data, GetInfoFromReaderAt skips the image data if
+// random access is possible.
+func (A AVIFParser) GetResolution(p []byte) (r Result, t Resolution) {
+	if result := A.DetectType(p); result != Valid {
+		return result, Resolution{}
+	}
+
+	return heifGetResolution(p)
+}
+
+// GetInfoFromReaderAt reads the size, orientation and pixel format from the start p
+// of the file and the resolution from the Exif item, which is read directly from r.
+func (A AVIFParser) GetInfoFromReaderAt(p []byte, r io.ReaderAt, size int64) (res Result, i ReaderAtInfo) {
+	if result := A.DetectType(p); result != Valid {
+		return result, ReaderAtInfo{}
+	}
+
+	return heifGetInfoFromReaderAt(p, r, size)
+}
+
+// GetColorProfile reads the color boxes of the primary item.
+func (A AVIFParser) GetColorProfile(p []byte) (r Result, c ColorProfile) {
+	if result := A.DetectType(p); result != Valid {
+		return result, ColorProfile{}
+	}
+
+	return heifGetColorProfile(p)
+}
+
+// GetAnimation reads the sample timing of AVIS image sequences.
+func (A AVIFParser) GetAnimation(p []byte, maxFrames int) (r Result, a Animation) {
+	if result := A.DetectType(p); result != Valid {
+		return result, Animation{}
+	}
+
+	return isobmffGetAnimation(p, maxFrames)
+}
+
+func init() {
+	register(&AVIFParser{})
+}
@@ -42,6 +42,108 @@ func (B BMPParser) GetSize(p []byte) (r Result, t ImageSize) {
 	return Valid, imageSize
 }
 
+// GetPixelFormat reads the bit count of the info header. Alpha is only reported if
+// the header has a non-zero alpha mask, which is part of version 3 and later
+// headers and of the BI_ALPHABITFIELDS compression.
+func (B BMPParser) GetPixelFormat(p []byte) (r Result, f PixelFormat) {
+	if result := B.DetectType(p); result != Valid {
+		return result, PixelFormat{}
+	}
+
+	if len(p) < 18 {
+		return NeedMoreData, PixelFormat{}
+	}
+
+	headerSize := binary.LittleEndian.Uint32(p[14:])
+
+	// OS/2 core header with 16 bit dimensions
+	if headerSize == 12 {
+		if len(p) < 26 {
+			return NeedMoreData, PixelFormat{}
+		}
+
+		return bmpPixelFormat(binary.LittleEndian.Uint16(p[24:]), false)
+	}
+
+	if len(p) < 34 {
+		return NeedMoreData, PixelFormat{}
+	}
+
+	bitCount := binary.LittleEndian.Uint16(p[28:])
+	compression := binary.LittleEndian.Uint32(p[30:])
+
+	// Embedded JPEG or PNG image
+	if compression == 4 || compression == 5 {
+		return Invalid, PixelFormat{}
+	}
+
+	alpha := false
+	if headerSize >= 56 || compression == 6 {
+		if len(p) < 70 {
+			return NeedMoreData, PixelFormat{}
+		}
+
+		alpha = binary.LittleEndian.Uint32(p[66:]) != 0
+	}
+
+	return bmpPixelFormat(bitCount, alpha)
+}
+
+// GetResolution reads the pixels per meter of the info header, which the OS/2 core
+// header does not store.
+func (B BMPParser) GetResolution(p []byte) (r Result, t Resolution) {
+	if result := B.DetectType(p); result != Valid {
+		return result, Resolution{}
+	}
+
+	if len(p) < 18 {
+		return NeedMoreData, Resolution{}
+	}
+
+	if headerSize := binary.LittleEndian.Uint32(p[14:]); headerSize < 40 {
+		return Valid, Resolution{}
+	}
+
+	if len(p) < 46 {
+		return NeedMoreData, Resolution{}
+	}
+
+	x := int32(binary.LittleEndian.Uint32(p[38:]))
+	y := int32(binary.LittleEndian.Uint32(p[42:]))
+	if x <= 0 || y <= 0 {
+		return Valid, Resolution{}
+	}
+
+	return Valid, resolutionPerMeter(uint32(x), uint32(y))
+}
+
+// bmpPixelFormat returns the pixel format of a bitmap with the given bits per pixel,
+// which is shared with the bitmaps stored inside of icons.
+func bmpPixelFormat(bitCount uint16, alpha bool) (Result, PixelFormat) {
+	pixelFormat := PixelFormat{}
+
+	switch bitCount {
+	case 1, 2, 4, 8:
+		pixelFormat = PixelFormat{BitDepth: uint8(bitCount), ColorModel: ColorModelPalette}
+	case 16:
+		// 5 bits per channel, unless bit fields say otherwise
+		pixelFormat = PixelFormat{BitDepth: 5, ColorModel: ColorModelRGB}
+	case 24, 32:
+		pixelFormat = PixelFormat{BitDepth: 8, ColorModel: ColorModelRGB}
+	default:
+		return Invalid, PixelFormat{}
+	}
+
+	pixelFormat.Channels = pixelFormatChannels(pixelFormat.ColorModel)
+
+	if alpha && pixelFormat.ColorModel == ColorModelRGB {
+		pixelFormat.Alpha = true
+		pixelFormat.Channels++
+	}
+
+	return Valid, pixelFormat
+}
+
 func init() {
 	register(&BMPParser{})
 }
@@ -0,0 +1,162 @@
+package parser
+
+import (
+	"bytes"
+	"encoding/binary"
+)
+
+// Information about the cr3 structure can be found here:
+// https://github.com/lclevy/canon_cr3
+
+type CR3Parser struct{}
+
+// User type of the uuid box holding the PRVW box
+var cr3PreviewUUID = []byte{
+	'\xea', '\xf4', '\x2b', '\x5e', '\x1c', '\x98', '\x4b', '\x88',
+	'\xb9', '\xfb', '\xb7', '\xdc', '\x40', '\x6e', '\x4d', '\x16',
+}
+
+func (C CR3Parser) Type() ImageType {
+	return CR3
+}
+
+func (C CR3Parser) DetectType(p []byte) (r Result) {
+	result, imageType := isobmffImageType(p)
+	if result != Valid {
+		return result
+	}
+
+	if imageType != CR3 {
+		return Invalid
+	}
+
+	return Valid
+}
+
+func (C CR3Parser) GetSize(p []byte) (r Result, t ImageSize) {
+	if result := C.DetectType(p); result != Valid {
+		return result, ImageSize{}
+	}
+
+	result, moov := isobmffFindTopLevelBox(p, "moov")
+	if result != Valid {
+		return result, ImageSize{}
+	}
+
+	return cr3SensorSize(p, moov)
+}
+
+// GetSizes returns the sensor size and the size of the preview stored in front of
+// the media data.
+func (C CR3Parser) GetSizes(p []byte) (r Result, s RAWSizes) {
+	if result := C.DetectType(p); result != Valid {
+		return result, RAWSizes{}
+	}
+
+	result, moov := isobmffFindTopLevelBox(p, "moov")
+	if result != Valid {
+		return result, RAWSizes{}
+	}
+
+	sizes := RAWSizes{}
+
+	result, sizes.Sensor = cr3SensorSize(p, moov)
+	if result != Valid {
+		return result, RAWSizes{}
+	}
+
+	// The preview is stored in a uuid box, which comes before the media data
+	for i := moov.end; ; {
+		result, box := isobmffFindBox(p, i, -1, "uuid", "mdat")
+		if result == Invalid || box.boxType == "mdat" {
+			break
+		}
+
+		if result != Valid {
+			return result, RAWSizes{}
+		}
+
+		if box.end == -1 {
+			break
+		}
+
+		if len(p) < box.end {
+			return NeedMoreData, RAWSizes{}
+		}
+
+		if box.end >= box.dataStart+16 && bytes.Equal(p[box.dataStart:box.dataStart+16], cr3PreviewUUID) {
+			// User type followed by 8 unknown bytes
+			result, prvw := isobmffFindBox(p, box.dataStart+16+8, box.end, "PRVW")
+			if result == Valid && prvw.end >= prvw.dataStart+10 {
+				sizes.Preview.Width = uint32(binary.BigEndian.Uint16(p[prvw.dataStart+6:]))
+				sizes.Preview.Height = uint32(binary.BigEndian.Uint16(p[prvw.dataStart+8:]))
+			}
+			break
+		}
+
+		i = box.end
+	}
+
+	return Valid, sizes
+}
+
+// cr3SensorSize returns the largest size of the CRAW sample entries, as the tracks
+// hold the full size jpeg, a small raw and the full raw image.
+func cr3SensorSize(p []byte, moov isobmffBox) (Result, ImageSize) {
+	result, boxes := isobmffChildren(p, moov.dataStart, moov.end)
+	if result != Valid {
+		return Invalid, ImageSize{}
+	}
+
+	largest := ImageSize{}
+
+	for _, trak := range boxes {
+		if trak.boxType != "trak" {
+			continue
+		}
+
+		box := trak
+		for _, boxType := range []string{"mdia", "minf", "stbl", "stsd"} {
+			result, box = isobmffFindBox(p, box.dataStart, box.end, boxType)
+			if result != Valid {
+				break
+			}
+		}
+
+		if result != Valid {
+			continue
+		}
+
+		// stsd is a full box followed by the entry count
+		result, entry := isobmffFindBox(p, box.dataStart+8, box.end, "CRAW")
+		if result != Valid {
+			continue
+		}
+
+		// Reserved fields and data reference index of the sample entry, followed
+		// by the predefined and reserved fields of the visual sample entry
+		i := entry.dataStart + 8 + 16
+		if entry.end < i+4 {
+			continue
+		}
+
+		size := ImageSize{
+			Width:  uint32(binary.BigEndian.Uint16(p[i:])),
+			Height: uint32(binary.BigEndian.Uint16(p[i+2:])),
+		}
+
+		if uint64(size.Width)*uint64(size.Height) > uint64(largest.Width)*uint64(largest.Height) {
+			largest = size
+		}
+	}
+
+	if largest.Width == 0 || largest.Height == 0 {
+		return Invalid, ImageSize{}
+	}
+
+	return Valid, largest
+}
+
+func init() {
+	register(&CR3Parser{})
+}
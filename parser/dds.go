@@ -0,0 +1,126 @@
+package parser
+
+import (
+	"bytes"
+	"encoding/binary"
+)
+
+// https://learn.microsoft.com/en-us/windows/win32/direct3ddds/dds-header
+// https://learn.microsoft.com/en-us/windows/win32/direct3ddds/dds-header-dxt10
+
+type DDSParser struct{}
+
+const (
+	ddsFlagMipMapCount = 0x20000
+	ddsFlagDepth       = 0x800000
+
+	ddsPixelFormatFourCC = 0x4
+
+	ddsCaps2Cubemap      = 0x200
+	ddsCaps2CubemapFaces = 0xfc00
+
+	ddsResourceMiscTextureCube = 0x4
+)
+
+func (D DDSParser) Type() ImageType {
+	return DDS
+}
+
+func (D DDSParser) DetectType(p []byte) (r Result) {
+	ddsMagic := []byte{'D', 'D', 'S', ' '}
+
+	if len(p) < len(ddsMagic) {
+		return NeedMoreData
+	}
+
+	if bytes.Equal(p[0:len(ddsMagic)], ddsMagic) {
+		return Valid
+	} else {
+		return Invalid
+	}
+}
+
+func (D DDSParser) GetSize(p []byte) (r Result, t ImageSize) {
+	result, textureInfo := D.GetTextureInfo(p)
+	if result != Valid {
+		return result, ImageSize{}
+	}
+
+	return Valid, textureInfo.Size
+}
+
+// GetTextureInfo reads the DDS header and the DX10 header, if present.
+func (D DDSParser) GetTextureInfo(p []byte) (r Result, t TextureInfo) {
+	if result := D.DetectType(p); result != Valid {
+		return result, TextureInfo{}
+	}
+
+	// Magic and DDS_HEADER
+	if len(p) < 4+124 {
+		return NeedMoreData, TextureInfo{}
+	}
+
+	if binary.LittleEndian.Uint32(p[4:]) != 124 {
+		return Invalid, TextureInfo{}
+	}
+
+	flags := binary.LittleEndian.Uint32(p[8:])
+	pixelFormatFlags := binary.LittleEndian.Uint32(p[80:])
+	caps2 := binary.LittleEndian.Uint32(p[112:])
+
+	textureInfo := TextureInfo{
+		Depth:       1,
+		ArrayLayers: 1,
+		Faces:       1,
+		MipLevels:   1,
+	}
+
+	textureInfo.Size.Height = binary.LittleEndian.Uint32(p[12:])
+	textureInfo.Size.Width = binary.LittleEndian.Uint32(p[16:])
+
+	if flags&ddsFlagDepth != 0 {
+		textureInfo.Depth = atLeastOne(binary.LittleEndian.Uint32(p[24:]))
+	}
+
+	if flags&ddsFlagMipMapCount != 0 {
+		textureInfo.MipLevels = atLeastOne(binary.LittleEndian.Uint32(p[28:]))
+	}
+
+	// Cube maps might only contain some of the faces
+	if caps2&ddsCaps2Cubemap != 0 {
+		textureInfo.Faces = 0
+		for faces := caps2 & ddsCaps2CubemapFaces; faces != 0; faces &= faces - 1 {
+			textureInfo.Faces++
+		}
+	}
+
+	if pixelFormatFlags&ddsPixelFormatFourCC == 0 {
+		return Valid, textureInfo
+	}
+
+	textureInfo.FourCC = string(bytes.TrimRight(p[84:88], "\x00 "))
+
+	if textureInfo.FourCC != "DX10" {
+		return Valid, textureInfo
+	}
+
+	// DDS_HEADER_DXT10
+	if len(p) < 4+124+20 {
+		return NeedMoreData, TextureInfo{}
+	}
+
+	i := 4 + 124
+	textureInfo.DXGIFormat = binary.LittleEndian.Uint32(p[i:])
+	miscFlag := binary.LittleEndian.Uint32(p[i+8:])
+	textureInfo.ArrayLayers = atLeastOne(binary.LittleEndian.Uint32(p[i+12:]))
+
+	if miscFlag&ddsResourceMiscTextureCube != 0 {
+		textureInfo.Faces = 6
+	}
+
+	return Valid, textureInfo
+}
+
+func init() {
+	register(&DDSParser{})
+}
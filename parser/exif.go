@@ -0,0 +1,449 @@
+package parser
+
+import (
+	"math"
+	"time"
+)
+
+// Information about the exif structure can be found here:
+// https://www.cipa.jp/std/documents/e/DC-008-Translation-2019-E.pdf
+// https://exiftool.org/TagNames/EXIF.html
+
+// Groups of EXIF fields, which are combined to select the fields to decode
+type EXIFFields uint
+
+const (
+	// Make, Model, Software and Orientation of IFD0
+	EXIFCamera EXIFFields = 1 << iota
+
+	// DateTimeOriginal and OffsetTimeOriginal of the Exif IFD
+	EXIFCaptureTime
+
+	// ExposureTime, FNumber, ISO, ExposureProgram, ExposureBias, Flash and
+	// FocalLength of the Exif IFD
+	EXIFExposure
+
+	// LensMake, LensModel and FocalLengthIn35mmFilm of the Exif IFD
+	EXIFLens
+
+	// Latitude, longitude and altitude of the GPS IFD
+	EXIFGPS
+
+	// InteroperabilityIndex of the Interoperability IFD
+	EXIFInterop
+
+	EXIFAll = EXIFCamera | EXIFCaptureTime | EXIFExposure | EXIFLens | EXIFGPS | EXIFInterop
+)
+
+type EXIF struct {
+	Make        string
+	Model       string
+	Software    string
+	Orientation Orientation
+
+	// Time the photo was taken. Without OffsetTimeOriginal the time zone is unknown
+	// and the time is returned in UTC.
+	DateTimeOriginal time.Time
+
+	// Exposure time in seconds
+	ExposureTime TIFFRational
+
+	FNumber         float64
+	ISO             uint32
+	ExposureProgram uint16
+
+	// Exposure bias in EV
+	ExposureBias float64
+
+	Flash uint16
+
+	// Focal length in millimeters
+	FocalLength           float64
+	FocalLengthIn35mmFilm uint16
+
+	LensMake  string
+	LensModel string
+
+	// Latitude and longitude in degrees, positive values are north and east. Only
+	// valid if HasGPS is set.
+	HasGPS    bool
+	Latitude  float64
+	Longitude float64
+
+	// Altitude in meters, negative values are below sea level
+	Altitude float64
+
+	// InteroperabilityIndex, e.g. R98 for DCF basic files
+	InteropIndex string
+}
+
+// Implemented by parsers of image formats which can contain EXIF data. Only the IFDs
+// needed for the requested fields are read, so less data is needed for fewer fields.
+// Invalid is returned if the image does not contain EXIF data.
+type EXIFParser interface {
+	GetEXIF(p []byte, fields EXIFFields) (Result, EXIF)
+}
+
+// EXIF tags referencing other IFDs
+const (
+	exifTagExifIFD    = 34665
+	exifTagGPSIFD     = 34853
+	exifTagInteropIFD = 40965
+)
+
+// exifReader reads tags of EXIF data, which starts with the TIFF header. The data
+// might not be complete yet, e.g. if it is read from a JPEG segment which is not
+// fully buffered.
+type exifReader struct {
+	p      []byte
+	header tiffHeader
+
+	// The data is complete, so missing data means it is corrupt
+	complete bool
+}
+
+func newEXIFReader(p []byte, complete bool) (Result, exifReader) {
+	reader := exifReader{p: p, complete: complete}
+
+	result, header := tiffReadHeader(p)
+	if result != Valid {
+		return reader.result(result), exifReader{}
+	}
+
+	if header.version != 42 && header.version != 43 {
+		return Invalid, exifReader{}
+	}
+
+	reader.header = header
+	return Valid, reader
+}
+
+func (e exifReader) result(r Result) Result {
+	if r == NeedMoreData && e.complete {
+		return Invalid
+	}
+
+	return r
+}
+
+func (e exifReader) readIFD(offset int) (Result, tiffIFD) {
+	result, ifd := tiffReadIFD(e.p, e.header, offset)
+	return e.result(result), ifd
+}
+
+// subIFD reads the IFD referenced by a pointer tag. Invalid is returned if the tag
+// is missing.
+func (e exifReader) subIFD(ifd tiffIFD, pointerTag int) (Result, tiffIFD) {
+	result, offset := tiffEntryValue(e.p, e.header.byteOrder, ifd, pointerTag)
+	if result != Valid {
+		return e.result(result), tiffIFD{}
+	}
+
+	return e.readIFD(offset)
+}
+
+// tags decodes the given tags of an IFD. Missing tags and tags which can not be
+// decoded are left out.
+func (e exifReader) tags(ifd tiffIFD, ids ...int) (Result, map[int]TIFFTag) {
+	tags := make(map[int]TIFFTag)
+
+	for _, id := range ids {
+		entry, ok := ifd.entries[id]
+		if !ok {
+			continue
+		}
+
+		result, tag := tiffDecodeEntry(e.p, e.header.byteOrder, entry)
+		if result == NeedMoreData {
+			return e.result(result), nil
+		}
+
+		if result == Valid {
+			tags[id] = tag
+		}
+	}
+
+	return Valid, tags
+}
+
+// Upper limit of the EXIF data read for the orientation, which is stored in the
+// first IFD
+const exifMaxOrientationSize = 64 * 1024
+
+// exifOrientation reads the Orientation tag from the first IFD of EXIF data.
+func exifOrientation(p []byte, complete bool) (Result, Orientation) {
+	result, reader := newEXIFReader(p, complete)
+	if result != Valid {
+		return result, OrientationNormal
+	}
+
+	result, ifd := reader.readIFD(reader.header.offsetFirstIFD)
+	if result != Valid {
+		return result, OrientationNormal
+	}
+
+	// Orientation
+	result, tags := reader.tags(ifd, 274)
+	if result != Valid {
+		return result, OrientationNormal
+	}
+
+	return Valid, exifTagOrientation(tags[274])
+}
+
+func exifTagOrientation(tag TIFFTag) Orientation {
+	value, ok := tag.Uint()
+	if !ok || value < uint64(OrientationNormal) || value > uint64(OrientationRotate270) {
+		return OrientationNormal
+	}
+
+	return Orientation(value)
+}
+
+// exifResolution reads the XResolution, YResolution and ResolutionUnit tags from the
+// first IFD of EXIF data. The unit defaults to inches.
+func exifResolution(p []byte, complete bool) (Result, Resolution) {
+	result, reader := newEXIFReader(p, complete)
+	if result != Valid {
+		return result, Resolution{}
+	}
+
+	result, ifd := reader.readIFD(reader.header.offsetFirstIFD)
+	if result != Valid {
+		return result, Resolution{}
+	}
+
+	// XResolution, YResolution and ResolutionUnit
+	result, tags := reader.tags(ifd, 282, 283, 296)
+	if result != Valid {
+		return result, Resolution{}
+	}
+
+	x, okX := tags[282].Float()
+	y, okY := tags[283].Float()
+	if !okX || !okY || x <= 0 || y <= 0 {
+		return Valid, Resolution{}
+	}
+
+	resolution := Resolution{X: x, Y: y, Unit: ResolutionUnitInch}
+
+	if unit, ok := tags[296].Uint(); ok {
+		switch unit {
+		case 1:
+			resolution.Unit = ResolutionUnitNone
+		case 3:
+			resolution.Unit = ResolutionUnitCentimeter
+		}
+	}
+
+	return Valid, resolution
+}
+
+// exifDecode reads the requested fields of EXIF data. Only the IFDs holding these
+// fields are read.
+func exifDecode(p []byte, complete bool, fields EXIFFields) (Result, EXIF) {
+	result, reader := newEXIFReader(p, complete)
+	if result != Valid {
+		return result, EXIF{}
+	}
+
+	result, ifd0 := reader.readIFD(reader.header.offsetFirstIFD)
+	if result != Valid {
+		return result, EXIF{}
+	}
+
+	exif := EXIF{Orientation: OrientationNormal}
+
+	if fields&EXIFCamera != 0 {
+		// Make, Model, Software and Orientation
+		result, tags := reader.tags(ifd0, 271, 272, 305, 274)
+		if result != Valid {
+			return result, EXIF{}
+		}
+
+		exif.Make = tags[271].String
+		exif.Model = tags[272].String
+		exif.Software = tags[305].String
+		exif.Orientation = exifTagOrientation(tags[274])
+	}
+
+	if fields&(EXIFCaptureTime|EXIFExposure|EXIFLens|EXIFInterop) != 0 {
+		result, exifIFD := reader.subIFD(ifd0, exifTagExifIFD)
+		if result == NeedMoreData {
+			return NeedMoreData, EXIF{}
+		}
+
+		if result == Valid {
+			if result := exifDecodeExifIFD(reader, exifIFD, fields, &exif); result != Valid {
+				return result, EXIF{}
+			}
+		}
+	}
+
+	if fields&EXIFGPS != 0 {
+		result, gpsIFD := reader.subIFD(ifd0, exifTagGPSIFD)
+		if result == NeedMoreData {
+			return NeedMoreData, EXIF{}
+		}
+
+		if result == Valid {
+			if result := exifDecodeGPSIFD(reader, gpsIFD, &exif); result != Valid {
+				return result, EXIF{}
+			}
+		}
+	}
+
+	return Valid, exif
+}
+
+func exifDecodeExifIFD(reader exifReader, ifd tiffIFD, fields EXIFFields, exif *EXIF) Result {
+	if fields&EXIFCaptureTime != 0 {
+		// DateTimeOriginal and OffsetTimeOriginal
+		result, tags := reader.tags(ifd, 36867, 36881)
+		if result != Valid {
+			return result
+		}
+
+		exif.DateTimeOriginal = exifParseTime(tags[36867].String, tags[36881].String)
+	}
+
+	if fields&EXIFExposure != 0 {
+		// ExposureTime, FNumber, ExposureProgram, ISO, ExposureBiasValue, Flash
+		// and FocalLength
+		result, tags := reader.tags(ifd, 33434, 33437, 34850, 34855, 37380, 37385, 37386)
+		if result != Valid {
+			return result
+		}
+
+		if len(tags[33434].Rationals) > 0 {
+			exif.ExposureTime = tags[33434].Rationals[0]
+		}
+
+		exif.FNumber, _ = tags[33437].Float()
+		exif.ExposureBias, _ = tags[37380].Float()
+		exif.FocalLength, _ = tags[37386].Float()
+
+		if value, ok := tags[34850].Uint(); ok {
+			exif.ExposureProgram = uint16(value)
+		}
+
+		if value, ok := tags[34855].Uint(); ok {
+			exif.ISO = uint32(value)
+		}
+
+		if value, ok := tags[37385].Uint(); ok {
+			exif.Flash = uint16(value)
+		}
+	}
+
+	if fields&EXIFLens != 0 {
+		// FocalLengthIn35mmFilm, LensMake and LensModel
+		result, tags := reader.tags(ifd, 41989, 42035, 42036)
+		if result != Valid {
+			return result
+		}
+
+		if value, ok := tags[41989].Uint(); ok {
+			exif.FocalLengthIn35mmFilm = uint16(value)
+		}
+
+		exif.LensMake = tags[42035].String
+		exif.LensModel = tags[42036].String
+	}
+
+	if fields&EXIFInterop != 0 {
+		result, interopIFD := reader.subIFD(ifd, exifTagInteropIFD)
+		if result == NeedMoreData {
+			return NeedMoreData
+		}
+
+		if result == Valid {
+			// InteroperabilityIndex
+			result, tags := reader.tags(interopIFD, 1)
+			if result != Valid {
+				return result
+			}
+
+			exif.InteropIndex = tags[1].String
+		}
+	}
+
+	return Valid
+}
+
+func exifDecodeGPSIFD(reader exifReader, ifd tiffIFD, exif *EXIF) Result {
+	// GPSLatitudeRef, GPSLatitude, GPSLongitudeRef, GPSLongitude, GPSAltitudeRef
+	// and GPSAltitude
+	result, tags := reader.tags(ifd, 1, 2, 3, 4, 5, 6)
+	if result != Valid {
+		return result
+	}
+
+	latitude, latitudeOk := exifParseCoordinate(tags[2])
+	longitude, longitudeOk := exifParseCoordinate(tags[4])
+
+	if latitudeOk && longitudeOk {
+		if tags[1].String == "S" {
+			latitude = -latitude
+		}
+
+		if tags[3].String == "W" {
+			longitude = -longitude
+		}
+
+		exif.HasGPS = true
+		exif.Latitude = latitude
+		exif.Longitude = longitude
+	}
+
+	if altitude, ok := tags[6].Float(); ok {
+		// Reference 1 means below sea level
+		if value, ok := tags[5].Uint(); ok && value == 1 {
+			altitude = -altitude
+		}
+
+		exif.Altitude = altitude
+	}
+
+	return Valid
+}
+
+// exifParseCoordinate converts degrees, minutes and seconds to degrees.
+func exifParseCoordinate(tag TIFFTag) (float64, bool) {
+	if len(tag.Rationals) != 3 {
+		return 0, false
+	}
+
+	degrees := tag.Rationals[0].Float() + tag.Rationals[1].Float()/60 + tag.Rationals[2].Float()/3600
+	if math.IsNaN(degrees) || degrees > 180 {
+		return 0, false
+	}
+
+	return degrees, true
+}
+
+// exifParseTime parses a date time value like 2006:01:02 15:04:05 and an optional
+// offset like +02:00.
+func exifParseTime(value string, offset string) time.Time {
+	if offset != "" {
+		if t, err := time.Parse("2006:01:02 15:04:05-07:00", value+offset); err == nil {
+			return t
+		}
+	}
+
+	t, err := time.Parse("2006:01:02 15:04:05", value)
+	if err != nil {
+		return time.Time{}
+	}
+
+	return t
+}
+
+// exifTrimIdentifier removes the "Exif\0\0" identifier in front of EXIF data.
+func exifTrimIdentifier(p []byte) []byte {
+	if len(p) >= 6 && string(p[:6]) == "Exif\x00\x00" {
+		return p[6:]
+	}
+
+	return p
+}
@@ -0,0 +1,246 @@
+package parser
+
+import (
+	"bytes"
+	"encoding/binary"
+	"strings"
+)
+
+// https://openexr.com/en/latest/OpenEXRFileLayout.html
+
+type EXRParser struct{}
+
+type EXRBox struct {
+	XMin int32
+	YMin int32
+	XMax int32
+	YMax int32
+}
+
+// Size returns the size of the window, which includes its min and max coordinates.
+func (b EXRBox) Size() ImageSize {
+	if b.XMax < b.XMin || b.YMax < b.YMin {
+		return ImageSize{}
+	}
+
+	return ImageSize{
+		Width:  uint32(int64(b.XMax) - int64(b.XMin) + 1),
+		Height: uint32(int64(b.YMax) - int64(b.YMin) + 1),
+	}
+}
+
+type EXRWindows struct {
+	// Bounds of the stored pixels
+	DataWindow EXRBox
+
+	// Bounds of the image as it should be displayed
+	DisplayWindow EXRBox
+}
+
+func (E EXRParser) Type() ImageType {
+	return EXR
+}
+
+func (E EXRParser) DetectType(p []byte) (r Result) {
+	exrMagic := []byte{'\x76', '\x2f', '\x31', '\x01'}
+
+	if len(p) < len(exrMagic) {
+		return NeedMoreData
+	}
+
+	if bytes.Equal(p[0:len(exrMagic)], exrMagic) {
+		return Valid
+	} else {
+		return Invalid
+	}
+}
+
+// GetSize returns the size of the data window.
+func (E EXRParser) GetSize(p []byte) (r Result, t ImageSize) {
+	result, windows := E.GetWindows(p)
+	if result != Valid {
+		return result, ImageSize{}
+	}
+
+	return Valid, windows.DataWindow.Size()
+}
+
+// GetWindows reads the dataWindow and displayWindow attributes of the first header.
+func (E EXRParser) GetWindows(p []byte) (r Result, w EXRWindows) {
+	if result := E.DetectType(p); result != Valid {
+		return result, EXRWindows{}
+	}
+
+	windows := EXRWindows{}
+
+	for _, name := range []string{"dataWindow", "displayWindow"} {
+		result, attributeType, data := exrFindAttribute(p, name)
+		if result != Valid {
+			return result, EXRWindows{}
+		}
+
+		if attributeType != "box2i" || len(data) != 16 {
+			return Invalid, EXRWindows{}
+		}
+
+		box := EXRBox{
+			XMin: int32(binary.LittleEndian.Uint32(data)),
+			YMin: int32(binary.LittleEndian.Uint32(data[4:])),
+			XMax: int32(binary.LittleEndian.Uint32(data[8:])),
+			YMax: int32(binary.LittleEndian.Uint32(data[12:])),
+		}
+
+		if name == "dataWindow" {
+			windows.DataWindow = box
+		} else {
+			windows.DisplayWindow = box
+		}
+	}
+
+	return Valid, windows
+}
+
+// GetPixelFormat reads the channel list of the first header. Only the channels of
+// the default layer are counted, the names of other channels are prefixed with the
+// name of their layer.
+func (E EXRParser) GetPixelFormat(p []byte) (r Result, f PixelFormat) {
+	if result := E.DetectType(p); result != Valid {
+		return result, PixelFormat{}
+	}
+
+	result, attributeType, data := exrFindAttribute(p, "channels")
+	if result != Valid {
+		return result, PixelFormat{}
+	}
+
+	if attributeType != "chlist" {
+		return Invalid, PixelFormat{}
+	}
+
+	pixelFormat := PixelFormat{}
+	channels := make(map[string]bool)
+
+	for i := 0; ; {
+		result, name, j := exrReadString(data, i)
+		if result != Valid {
+			return Invalid, PixelFormat{}
+		}
+
+		// An empty name marks the end of the list
+		if name == "" {
+			break
+		}
+
+		// Pixel type, linear flag, three reserved bytes and the sampling rates
+		if len(data) < j+16 {
+			return Invalid, PixelFormat{}
+		}
+
+		pixelType := binary.LittleEndian.Uint32(data[j:])
+		i = j + 16
+
+		if strings.Contains(name, ".") {
+			continue
+		}
+
+		channels[name] = true
+		pixelFormat.Channels++
+
+		// UINT and FLOAT use 32 bits, HALF 16 bits
+		bitDepth := uint8(32)
+		if pixelType == 1 {
+			bitDepth = 16
+		}
+
+		if bitDepth > pixelFormat.BitDepth {
+			pixelFormat.BitDepth = bitDepth
+		}
+	}
+
+	switch {
+	case channels["R"] && channels["G"] && channels["B"]:
+		pixelFormat.ColorModel = ColorModelRGB
+	case channels["Y"] && channels["RY"] && channels["BY"]:
+		pixelFormat.ColorModel = ColorModelYCbCr
+	case channels["Y"]:
+		pixelFormat.ColorModel = ColorModelGray
+	}
+
+	pixelFormat.Alpha = channels["A"]
+
+	return Valid, pixelFormat
+}
+
+// exrFindAttribute walks the attributes of the first header until the attribute with
+// the given name is found and returns its type and value.
+func exrFindAttribute(p []byte, name string) (Result, string, []byte) {
+	// Magic number and version field
+	i := 8
+	if len(p) < i {
+		return NeedMoreData, "", nil
+	}
+
+	for {
+		result, attributeName, j := exrReadString(p, i)
+		if result != Valid {
+			return result, "", nil
+		}
+
+		// An empty name marks the end of the header
+		if attributeName == "" {
+			return Invalid, "", nil
+		}
+
+		result, attributeType, j := exrReadString(p, j)
+		if result != Valid {
+			return result, "", nil
+		}
+
+		if len(p) < j+4 {
+			return NeedMoreData, "", nil
+		}
+
+		size := int(binary.LittleEndian.Uint32(p[j:]))
+		j += 4
+
+		if size < 0 || j+size < j {
+			return Invalid, "", nil
+		}
+
+		if attributeName == name {
+			if len(p) < j+size {
+				return NeedMoreData, "", nil
+			}
+
+			return Valid, attributeType, p[j : j+size]
+		}
+
+		i = j + size
+	}
+}
+
+// exrReadString reads a null terminated string with a maximum length of 255 bytes.
+func exrReadString(p []byte, i int) (Result, string, int) {
+	end := i + 256
+	if end > len(p) {
+		end = len(p)
+	}
+
+	if i >= end {
+		return NeedMoreData, "", 0
+	}
+
+	length := bytes.IndexByte(p[i:end], 0)
+	if length == -1 {
+		if end == len(p) {
+			return NeedMoreData, "", 0
+		}
+		return Invalid, "", 0
+	}
+
+	return Valid, string(p[i : i+length]), i + length + 1
+}
+
+func init() {
+	register(&EXRParser{})
+}
@@ -3,6 +3,7 @@ package parser
 import (
 	"bytes"
 	"encoding/binary"
+	"time"
 )
 
 type GIFParser struct{}
@@ -41,6 +42,256 @@ func (G GIFParser) GetSize(p []byte) (r Result, t ImageSize) {
 	return Valid, imageSize
 }
 
+// GetPixelFormat reads the size of the global color table, or the color resolution
+// if there is none. GIF images are transparent if the graphic control extension in
+// front of the first image has the transparent color flag set.
+func (G GIFParser) GetPixelFormat(p []byte) (r Result, f PixelFormat) {
+	if result := G.DetectType(p); result != Valid {
+		return result, PixelFormat{}
+	}
+
+	if len(p) < 13 {
+		return NeedMoreData, PixelFormat{}
+	}
+
+	packed := p[10]
+
+	pixelFormat := PixelFormat{
+		BitDepth:   (packed>>4)&0x07 + 1,
+		Channels:   1,
+		ColorModel: ColorModelPalette,
+	}
+
+	if packed&0x80 != 0 {
+		pixelFormat.BitDepth = packed&0x07 + 1
+	}
+
+	// Extensions in front of the first image, without reading the image data
+	for i := 13 + gifColorTableSize(packed); ; {
+		if len(p) < i+2 {
+			return NeedMoreData, PixelFormat{}
+		}
+
+		if p[i] != '\x21' {
+			return Valid, pixelFormat
+		}
+
+		// Graphic control extension, the flag is stored in the packed fields
+		// following the block size
+		if p[i+1] == '\xf9' {
+			if len(p) < i+4 {
+				return NeedMoreData, PixelFormat{}
+			}
+
+			pixelFormat.Alpha = p[i+3]&0x01 == 1
+			return Valid, pixelFormat
+		}
+
+		result, end := gifSkipSubBlocks(p, i+2)
+		if result != Valid {
+			return result, PixelFormat{}
+		}
+
+		i = end
+	}
+}
+
+// GetXMP returns the XMP packet stored in the application extension with the
+// identifier XMP DataXMP. The packet is stored as is, followed by a trailer which
+// makes it readable as data sub-blocks.
+func (G GIFParser) GetXMP(p []byte) (r Result, x []byte) {
+	if result := G.DetectType(p); result != Valid {
+		return result, nil
+	}
+
+	xmpIdentifier := []byte("\x0bXMP DataXMP")
+
+	var packet []byte
+
+	result := gifWalkBlocks(p, func(block gifBlock) bool {
+		if block.introducer != '\x21' || block.label != '\xff' {
+			return false
+		}
+
+		data := p[block.dataStart:block.end]
+		if !bytes.HasPrefix(data, xmpIdentifier) {
+			return false
+		}
+
+		data = data[len(xmpIdentifier):]
+
+		// The trailer counts down from 0xff to 0x00
+		end := bytes.Index(data, []byte{'\x01', '\xff', '\xfe'})
+		if end == -1 {
+			end = len(data)
+		}
+
+		packet = data[:end]
+		return true
+	})
+
+	if result != Valid {
+		return result, nil
+	}
+
+	if packet == nil {
+		return Invalid, nil
+	}
+
+	return Valid, packet
+}
+
+// GetAnimation counts the images of the file. The delay of a graphic control
+// extension belongs to the image following it. The NETSCAPE2.0 application extension
+// stores how often the animation is repeated after it has been played, 0 means
+// forever. Without it, the animation is played once.
+func (G GIFParser) GetAnimation(p []byte, maxFrames int) (r Result, a Animation) {
+	if result := G.DetectType(p); result != Valid {
+		return result, Animation{}
+	}
+
+	loopIdentifiers := [][]byte{[]byte("\x0bNETSCAPE2.0\x03\x01"), []byte("\x0bANIMEXTS1.0\x03\x01")}
+
+	animation := Animation{Loops: 1}
+	var delay time.Duration
+
+	result := gifWalkBlocks(p, func(block gifBlock) bool {
+		data := p[block.dataStart:block.end]
+
+		switch {
+		case block.introducer == '\x2c':
+			frameDelay := delay
+			delay = 0
+			return animationAddFrame(&animation, frameDelay, maxFrames)
+		case block.label == '\xf9':
+			// Block size, packed fields and the delay in hundredths of a second
+			if len(data) >= 4 {
+				delay = time.Duration(binary.LittleEndian.Uint16(data[2:])) * 10 * time.Millisecond
+			}
+		case block.label == '\xff':
+			for _, loopIdentifier := range loopIdentifiers {
+				if bytes.HasPrefix(data, loopIdentifier) && len(data) >= len(loopIdentifier)+2 {
+					animation.Loops = int(binary.LittleEndian.Uint16(data[len(loopIdentifier):]))
+					if animation.Loops > 0 {
+						animation.Loops++
+					}
+				}
+			}
+		}
+
+		return false
+	})
+
+	if result != Valid {
+		return result, Animation{}
+	}
+
+	if animation.Frames == 0 {
+		return Invalid, Animation{}
+	}
+
+	// Loop counts only matter for animations
+	animation.Animated = animation.Frames > 1 || animation.Truncated
+	if !animation.Animated {
+		animation.Loops = 0
+	}
+
+	return Valid, animation
+}
+
+type gifBlock struct {
+	// Extension introducer (0x21) or image separator (0x2c)
+	introducer byte
+
+	// Label of extensions
+	label byte
+
+	// Offset of the introducer
+	start int
+
+	// Offset of the first data sub-block and the offset after the block terminator
+	dataStart int
+	end       int
+}
+
+// gifColorTableSize returns the size of a color table from the packed fields of the
+// logical screen descriptor or an image descriptor.
+func gifColorTableSize(packed byte) int {
+	if packed&0x80 == 0 {
+		return 0
+	}
+
+	return 3 * (1 << (packed&0x07 + 1))
+}
+
+// gifWalkBlocks calls fn for every extension and image following the logical screen
+// descriptor, until fn returns true or the trailer is reached.
+func gifWalkBlocks(p []byte, fn func(block gifBlock) bool) Result {
+	// Header and logical screen descriptor
+	if len(p) < 13 {
+		return NeedMoreData
+	}
+
+	i := 13 + gifColorTableSize(p[10])
+
+	for {
+		if len(p) < i+1 {
+			return NeedMoreData
+		}
+
+		block := gifBlock{introducer: p[i], start: i}
+
+		switch block.introducer {
+		case '\x3b':
+			// Trailer
+			return Valid
+		case '\x21':
+			if len(p) < i+2 {
+				return NeedMoreData
+			}
+			block.label = p[i+1]
+			block.dataStart = i + 2
+		case '\x2c':
+			// Image descriptor, local color table and LZW minimum code size
+			if len(p) < i+10 {
+				return NeedMoreData
+			}
+			block.dataStart = i + 10 + gifColorTableSize(p[i+9]) + 1
+		default:
+			return Invalid
+		}
+
+		result, end := gifSkipSubBlocks(p, block.dataStart)
+		if result != Valid {
+			return result
+		}
+		block.end = end
+
+		if fn(block) {
+			return Valid
+		}
+
+		i = block.end
+	}
+}
+
+// gifSkipSubBlocks returns the offset after the block terminator of the data
+// sub-blocks starting at offset i.
+func gifSkipSubBlocks(p []byte, i int) (Result, int) {
+	for {
+		if len(p) < i+1 {
+			return NeedMoreData, 0
+		}
+
+		size := int(p[i])
+		i += 1 + size
+
+		if size == 0 {
+			return Valid, i
+		}
+	}
+}
+
 func init() {
 	register(&GIFParser{})
 }
@@ -0,0 +1,134 @@
+package parser
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+)
+
+// https://radsite.lbl.gov/radiance/refer/filefmts.pdf
+// https://paulbourke.net/dataformats/pic/
+
+type HDRParser struct{}
+
+type HDRHeader struct {
+	Size ImageSize
+
+	// Value of the FORMAT line, e.g. 32-bit_rle_rgbe
+	Format string
+
+	// Order and direction of the axes as given by the resolution line, e.g. -Y +X
+	// for the standard orientation. If X comes first, scanlines are columns.
+	Orientation string
+}
+
+func (H HDRParser) Type() ImageType {
+	return HDR
+}
+
+func (H HDRParser) DetectType(p []byte) (r Result) {
+	for _, signature := range []string{"#?RADIANCE\n", "#?RGBE\n"} {
+		if len(p) < len(signature) {
+			if strings.HasPrefix(signature, string(p)) {
+				return NeedMoreData
+			}
+			continue
+		}
+
+		if string(p[:len(signature)]) == signature {
+			return Valid
+		}
+	}
+
+	return Invalid
+}
+
+func (H HDRParser) GetSize(p []byte) (r Result, t ImageSize) {
+	result, header := H.GetHeader(p)
+	if result != Valid {
+		return result, ImageSize{}
+	}
+
+	return Valid, header.Size
+}
+
+// GetHeader reads the header lines and the resolution line following them.
+func (H HDRParser) GetHeader(p []byte) (r Result, h HDRHeader) {
+	if result := H.DetectType(p); result != Valid {
+		return result, HDRHeader{}
+	}
+
+	header := HDRHeader{}
+
+	// Skip the signature line
+	i := bytes.IndexByte(p, '\n') + 1
+
+	// Header lines are terminated by an empty line
+	for {
+		end := bytes.IndexByte(p[i:], '\n')
+		if end == -1 {
+			return NeedMoreData, HDRHeader{}
+		}
+
+		line := string(p[i : i+end])
+		i += end + 1
+
+		if line == "" {
+			break
+		}
+
+		if strings.HasPrefix(line, "FORMAT=") {
+			header.Format = strings.TrimPrefix(line, "FORMAT=")
+		}
+	}
+
+	// Resolution line
+	end := bytes.IndexByte(p[i:], '\n')
+	if end == -1 {
+		return NeedMoreData, HDRHeader{}
+	}
+
+	fields := strings.Fields(string(p[i : i+end]))
+	if len(fields) != 4 {
+		return Invalid, HDRHeader{}
+	}
+
+	var width, height uint64
+	hasWidth, hasHeight := false, false
+
+	for j := 0; j < 4; j += 2 {
+		axis := fields[j]
+		if len(axis) != 2 || (axis[0] != '-' && axis[0] != '+') {
+			return Invalid, HDRHeader{}
+		}
+
+		value, err := strconv.ParseUint(fields[j+1], 10, 32)
+		if err != nil {
+			return Invalid, HDRHeader{}
+		}
+
+		switch axis[1] {
+		case 'X':
+			width = value
+			hasWidth = true
+		case 'Y':
+			height = value
+			hasHeight = true
+		default:
+			return Invalid, HDRHeader{}
+		}
+	}
+
+	if !hasWidth || !hasHeight {
+		return Invalid, HDRHeader{}
+	}
+
+	header.Size = ImageSize{Width: uint32(width), Height: uint32(height)}
+	header.Orientation = fields[0] + " " + fields[2]
+
+	return Valid, header
+}
+
+func init() {
+	register(&HDRParser{})
+}
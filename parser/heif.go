@@ -0,0 +1,1068 @@
+package parser
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+)
+
+// https://nokiatech.github.io/heif/technical.html
+// https://www.iso.org/standard/83650.html
+
+// heifParser implements the methods shared by the parsers of the image formats based
+// on HEIF. They accept HEIC and HEIF files, the DetectType method of each parser
+// tells them apart.
+type heifParser struct{}
+
+type HEICParser struct {
+	heifParser
+}
+
+func (H HEICParser) Type() ImageType {
+	return HEIC
+}
+
+func (H HEICParser) DetectType(p []byte) (r Result) {
+	return heifDetectType(p, HEIC)
+}
+
+type HEIFParser struct {
+	heifParser
+}
+
+func (H HEIFParser) Type() ImageType {
+	return HEIF
+}
+
+func (H HEIFParser) DetectType(p []byte) (r Result) {
+	return heifDetectType(p, HEIF)
+}
+
+func (H heifParser) GetSize(p []byte) (r Result, t ImageSize) {
+	if result := H.detectType(p); result != Valid {
+		return result, ImageSize{}
+	}
+
+	return heifGetSize(p)
+}
+
+// GetOrientation combines the rotation and mirror properties of the primary item.
+func (H heifParser) GetOrientation(p []byte) (r Result, o Orientation) {
+	if result := H.detectType(p); result != Valid {
+		return result, OrientationNormal
+	}
+
+	return heifGetOrientation(p)
+}
+
+// GetEXIF decodes the requested fields of the Exif item.
+func (H heifParser) GetEXIF(p []byte, fields EXIFFields) (r Result, e EXIF) {
+	if result := H.detectType(p); result != Valid {
+		return result, EXIF{}
+	}
+
+	return heifGetEXIF(p, fields)
+}
+
+// GetResolution reads the resolution tags of the EXIF data. The Exif item might be
+// stored after the coded image data, GetInfoFromReaderAt skips the image data if
+// random access is possible.
+func (H heifParser) GetResolution(p []byte) (r Result, t Resolution) {
+	if result := H.detectType(p); result != Valid {
+		return result, Resolution{}
+	}
+
+	return heifGetResolution(p)
+}
+
+// GetColorProfile reads the color boxes of the primary item.
+func (H heifParser) GetColorProfile(p []byte) (r Result, c ColorProfile) {
+	if result := H.detectType(p); result != Valid {
+		return result, ColorProfile{}
+	}
+
+	return heifGetColorProfile(p)
+}
+
+// GetPixelFormat reads the pixel information and decoder configuration of the
+// primary item.
+func (H heifParser) GetPixelFormat(p []byte) (r Result, f PixelFormat) {
+	if result := H.detectType(p); result != Valid {
+		return result, PixelFormat{}
+	}
+
+	return heifGetPixelFormat(p)
+}
+
+// GetAnimation reads the sample timing of image sequences.
+func (H heifParser) GetAnimation(p []byte, maxFrames int) (r Result, a Animation) {
+	if result := H.detectType(p); result != Valid {
+		return result, Animation{}
+	}
+
+	return isobmffGetAnimation(p, maxFrames)
+}
+
+// GetInfoFromReaderAt reads the size, orientation and pixel format from the start p
+// of the file and the resolution from the Exif item, which is read directly from r.
+func (H heifParser) GetInfoFromReaderAt(p []byte, r io.ReaderAt, size int64) (res Result, i ReaderAtInfo) {
+	if result := H.detectType(p); result != Valid {
+		return result, ReaderAtInfo{}
+	}
+
+	return heifGetInfoFromReaderAt(p, r, size)
+}
+
+// detectType accepts both HEIC and HEIF files.
+func (H heifParser) detectType(p []byte) Result {
+	result, imageType := isobmffImageType(p)
+	if result != Valid {
+		return result
+	}
+
+	if imageType != HEIC && imageType != HEIF {
+		return Invalid
+	}
+
+	return Valid
+}
+
+func heifDetectType(p []byte, expectedImageType ImageType) Result {
+	result, imageType := isobmffImageType(p)
+	if result != Valid {
+		return result
+	}
+
+	if imageType != expectedImageType {
+		return Invalid
+	}
+
+	return Valid
+}
+
+// heifGetSize returns the size of the primary item, which is shared by all image
+// formats based on HEIF.
+func heifGetSize(p []byte) (Result, ImageSize) {
+	// Image sequences might only describe their size inside of the track header
+	result, box := isobmffFindTopLevelBox(p, "meta", "moov")
+	if result != Valid {
+		return result, ImageSize{}
+	}
+
+	if box.boxType == "moov" {
+		return isobmffTrackSize(p, box)
+	}
+
+	result, meta := heifParseMeta(p, box)
+	if result != Valid {
+		return result, ImageSize{}
+	}
+
+	return meta.itemSize(p, meta.primaryItemID, 0)
+}
+
+// heifGetOrientation applies the transformative properties irot and imir of the
+// primary item in the order of their association.
+func heifGetOrientation(p []byte) (Result, Orientation) {
+	result, box := isobmffFindTopLevelBox(p, "meta", "moov")
+	if result != Valid {
+		return result, OrientationNormal
+	}
+
+	if box.boxType == "moov" {
+		return Valid, OrientationNormal
+	}
+
+	result, meta := heifParseMeta(p, box)
+	if result != Valid {
+		return result, OrientationNormal
+	}
+
+	orientation := OrientationNormal
+
+	for _, index := range meta.associations[meta.primaryItemID] {
+		if index >= len(meta.properties) {
+			continue
+		}
+
+		property := meta.properties[index]
+		if property.end < property.dataStart+1 {
+			continue
+		}
+
+		switch property.boxType {
+		case "irot":
+			// Anti-clockwise rotation in quarter turns
+			angle := int(p[property.dataStart] & 0x03)
+			orientation = orientationRotate(orientation, (4-angle)%4)
+		case "imir":
+			// Axis 0 is the vertical axis, which flips left and right
+			orientation = orientationFlip(orientation, p[property.dataStart]&0x01 == 1)
+		}
+	}
+
+	return Valid, orientation
+}
+
+// heifGetEXIF decodes the Exif item with the lowest item id.
+func heifGetEXIF(p []byte, fields EXIFFields) (Result, EXIF) {
+	result, data := heifEXIFData(p)
+	if result != Valid {
+		return result, EXIF{}
+	}
+
+	return exifDecode(data, true, fields)
+}
+
+// Upper limit of the Exif item size read by GetInfoFromReaderAt
+const heifMaxEXIFSize = 1024 * 1024
+
+// heifGetInfoFromReaderAt reads the image info of all image formats based on HEIF,
+// with the Exif item read directly from r.
+func heifGetInfoFromReaderAt(p []byte, r io.ReaderAt, size int64) (Result, ReaderAtInfo) {
+	result, imageSize := heifGetSize(p)
+	if result != Valid {
+		return result, ReaderAtInfo{}
+	}
+
+	info := ReaderAtInfo{Size: imageSize, Orientation: OrientationNormal}
+
+	result, orientation := heifGetOrientation(p)
+	if result == NeedMoreData {
+		return NeedMoreData, ReaderAtInfo{}
+	}
+
+	if result == Valid {
+		info.Orientation = orientation
+	}
+
+	result, pixelFormat := heifGetPixelFormat(p)
+	if result == NeedMoreData {
+		return NeedMoreData, ReaderAtInfo{}
+	}
+
+	if result == Valid {
+		info.PixelFormat = pixelFormat
+	}
+
+	result, meta, exifItemID := heifEXIFItem(p)
+	if result == NeedMoreData {
+		return NeedMoreData, ReaderAtInfo{}
+	}
+
+	if result != Valid {
+		return Valid, info
+	}
+
+	result, data := meta.itemDataAt(p, r, size, exifItemID, heifMaxEXIFSize)
+	if result != Valid {
+		return Valid, info
+	}
+
+	result, data = heifEXIFPayload(data)
+	if result != Valid {
+		return Valid, info
+	}
+
+	if result, resolution := exifResolution(data, true); result == Valid {
+		info.Resolution = resolution
+	}
+
+	return Valid, info
+}
+
+// heifGetResolution reads the resolution tags of the Exif item, as HEIF does not
+// define a property for the resolution. Images without an Exif item are reported as
+// Valid with an empty resolution.
+func heifGetResolution(p []byte) (Result, Resolution) {
+	result, data := heifEXIFData(p)
+	if result == NeedMoreData {
+		return NeedMoreData, Resolution{}
+	}
+
+	if result != Valid {
+		return Valid, Resolution{}
+	}
+
+	return exifResolution(data, true)
+}
+
+// heifEXIFData returns the EXIF data of the Exif item with the lowest item id.
+func heifEXIFData(p []byte) (Result, []byte) {
+	result, meta, exifItemID := heifEXIFItem(p)
+	if result != Valid {
+		return result, nil
+	}
+
+	result, data := meta.itemData(p, exifItemID)
+	if result != Valid {
+		return result, nil
+	}
+
+	return heifEXIFPayload(data)
+}
+
+// heifEXIFItem returns the parsed meta box and the id of the Exif item with the
+// lowest item id. Invalid is returned if there is no Exif item.
+func heifEXIFItem(p []byte) (Result, heifMeta, uint32) {
+	result, box := isobmffFindTopLevelBox(p, "meta", "moov")
+	if result != Valid {
+		return result, heifMeta{}, 0
+	}
+
+	if box.boxType == "moov" {
+		return Invalid, heifMeta{}, 0
+	}
+
+	result, meta := heifParseMeta(p, box)
+	if result != Valid {
+		return result, heifMeta{}, 0
+	}
+
+	found := false
+	var exifItemID uint32
+
+	for itemID, itemType := range meta.itemTypes {
+		if itemType == "Exif" && (!found || itemID < exifItemID) {
+			found = true
+			exifItemID = itemID
+		}
+	}
+
+	if !found {
+		return Invalid, heifMeta{}, 0
+	}
+
+	return Valid, meta, exifItemID
+}
+
+// heifEXIFPayload skips the offset of the TIFF header at the start of the Exif item
+// data, which usually skips the EXIF identifier.
+func heifEXIFPayload(data []byte) (Result, []byte) {
+	if len(data) < 4 {
+		return Invalid, nil
+	}
+
+	offset := int(binary.BigEndian.Uint32(data)) + 4
+	if offset < 4 || len(data) < offset {
+		return Invalid, nil
+	}
+
+	return Valid, data[offset:]
+}
+
+// heifGetColorProfile reads the color boxes of the primary item, or of its first
+// input image for derived images. An item might have both an ICC profile and an
+// nclx color box.
+func heifGetColorProfile(p []byte) (Result, ColorProfile) {
+	result, box := isobmffFindTopLevelBox(p, "meta", "moov")
+	if result != Valid {
+		return result, ColorProfile{}
+	}
+
+	if box.boxType == "moov" {
+		return Valid, ColorProfile{}
+	}
+
+	result, meta := heifParseMeta(p, box)
+	if result != Valid {
+		return result, ColorProfile{}
+	}
+
+	itemID := meta.primaryItemID
+	if _, ok := meta.itemProperty(itemID, "colr"); !ok {
+		if sources := meta.derivedFrom[itemID]; len(sources) > 0 {
+			itemID = sources[0]
+		}
+	}
+
+	colorProfile := ColorProfile{}
+
+	for _, index := range meta.associations[itemID] {
+		if index >= len(meta.properties) || meta.properties[index].boxType != "colr" {
+			continue
+		}
+
+		colr := meta.properties[index]
+		if colr.end < colr.dataStart+4 {
+			return Invalid, ColorProfile{}
+		}
+
+		switch data := p[colr.dataStart+4 : colr.end]; string(p[colr.dataStart : colr.dataStart+4]) {
+		case "prof", "rICC":
+			iccProfile := iccColorProfile(data)
+			colorProfile.ICC = iccProfile.ICC
+			colorProfile.Description = iccProfile.Description
+			colorProfile.ColorSpace = iccProfile.ColorSpace
+		case "nclx":
+			// Primaries, transfer characteristics, matrix coefficients and the full
+			// range flag in the highest bit
+			if len(data) < 7 {
+				return Invalid, ColorProfile{}
+			}
+
+			colorProfile.HasCICP = true
+			colorProfile.CICP = CICP{
+				ColorPrimaries:          binary.BigEndian.Uint16(data),
+				TransferCharacteristics: binary.BigEndian.Uint16(data[2:]),
+				MatrixCoefficients:      binary.BigEndian.Uint16(data[4:]),
+				FullRange:               data[6]&0x80 != 0,
+			}
+		}
+	}
+
+	return Valid, colorProfile
+}
+
+// Types of auxiliary images holding the alpha channel, as defined for AVIF and HEVC
+var heifAlphaAuxiliaryTypes = []string{
+	"urn:mpeg:mpegB:cicp:systems:auxiliary:alpha",
+	"urn:mpeg:hevc:2015:auxid:1",
+}
+
+// heifGetPixelFormat reads the pixel information property of the primary item, or
+// of its first input image for derived images. Without it, the bit depth and the
+// chroma format are taken from the decoder configuration. The image has alpha if an
+// auxiliary alpha image belongs to the primary item.
+func heifGetPixelFormat(p []byte) (Result, PixelFormat) {
+	result, box := isobmffFindTopLevelBox(p, "meta", "moov")
+	if result != Valid {
+		return result, PixelFormat{}
+	}
+
+	if box.boxType == "moov" {
+		return Invalid, PixelFormat{}
+	}
+
+	result, meta := heifParseMeta(p, box)
+	if result != Valid {
+		return result, PixelFormat{}
+	}
+
+	itemProperty := func(boxType string) (isobmffBox, bool) {
+		if property, ok := meta.itemProperty(meta.primaryItemID, boxType); ok {
+			return property, true
+		}
+
+		if sources := meta.derivedFrom[meta.primaryItemID]; len(sources) > 0 {
+			return meta.itemProperty(sources[0], boxType)
+		}
+
+		return isobmffBox{}, false
+	}
+
+	pixelFormat := PixelFormat{}
+	monochrome := false
+
+	if pixi, ok := itemProperty("pixi"); ok && pixi.end >= pixi.dataStart+6 {
+		// Full box with the number of channels and the bits per channel
+		pixelFormat.Channels = uint16(p[pixi.dataStart+4])
+		pixelFormat.BitDepth = p[pixi.dataStart+5]
+		monochrome = pixelFormat.Channels == 1
+	} else if av1C, ok := itemProperty("av1C"); ok && av1C.end >= av1C.dataStart+3 {
+		// high_bitdepth, twelve_bit and mono_chrome
+		flags := p[av1C.dataStart+2]
+		pixelFormat.BitDepth = 8
+		if flags&0x40 != 0 {
+			pixelFormat.BitDepth = 10
+			if flags&0x20 != 0 {
+				pixelFormat.BitDepth = 12
+			}
+		}
+		monochrome = flags&0x10 != 0
+	} else if hvcC, ok := itemProperty("hvcC"); ok && hvcC.end >= hvcC.dataStart+18 {
+		// chromaFormat and bitDepthLumaMinus8
+		monochrome = p[hvcC.dataStart+16]&0x03 == 0
+		pixelFormat.BitDepth = p[hvcC.dataStart+17]&0x07 + 8
+	} else {
+		return Invalid, PixelFormat{}
+	}
+
+	pixelFormat.ColorModel = ColorModelYCbCr
+	if monochrome {
+		pixelFormat.ColorModel = ColorModelGray
+	}
+
+	// Matrix coefficients of 0 mean the samples are stored as RGB
+	if colr, ok := itemProperty("colr"); ok && !monochrome && colr.end >= colr.dataStart+10 {
+		i := colr.dataStart
+		if string(p[i:i+4]) == "nclx" && binary.BigEndian.Uint16(p[i+8:]) == 0 {
+			pixelFormat.ColorModel = ColorModelRGB
+		}
+	}
+
+	if pixelFormat.Channels == 0 {
+		pixelFormat.Channels = pixelFormatChannels(pixelFormat.ColorModel)
+	}
+
+	for itemID, masterItemIDs := range meta.auxiliaryFor {
+		if !heifIsAlpha(p, meta, itemID) {
+			continue
+		}
+
+		for _, masterItemID := range masterItemIDs {
+			if masterItemID == meta.primaryItemID {
+				pixelFormat.Alpha = true
+			}
+		}
+	}
+
+	if pixelFormat.Alpha {
+		pixelFormat.Channels++
+	}
+
+	return Valid, pixelFormat
+}
+
+// heifIsAlpha checks the auxiliary type property of an item for an alpha channel.
+func heifIsAlpha(p []byte, meta heifMeta, itemID uint32) bool {
+	auxC, ok := meta.itemProperty(itemID, "auxC")
+	if !ok || auxC.end < auxC.dataStart+4 {
+		return false
+	}
+
+	// Full box with a null terminated type, optionally followed by subtype data
+	auxType := p[auxC.dataStart+4 : auxC.end]
+	if end := bytes.IndexByte(auxType, 0); end != -1 {
+		auxType = auxType[:end]
+	}
+
+	for _, alphaType := range heifAlphaAuxiliaryTypes {
+		if string(auxType) == alphaType {
+			return true
+		}
+	}
+
+	return false
+}
+
+type heifItemLocation struct {
+	constructionMethod int
+	offset             int
+	length             int
+}
+
+type heifMeta struct {
+	primaryItemID uint32
+
+	// Item types (e.g. hvc1, av01, grid, iden) per item id
+	itemTypes map[uint32]string
+
+	// Derived image references (dimg) per item id
+	derivedFrom map[uint32][]uint32
+
+	// Auxiliary image references (auxl) per item id, pointing to the master images
+	auxiliaryFor map[uint32][]uint32
+
+	// Location of the first extent per item id
+	locations map[uint32]heifItemLocation
+
+	// Item data box, used by items with construction method 1
+	idat isobmffBox
+
+	// Boxes of the ipco container, referenced by their index
+	properties []isobmffBox
+
+	// Property indexes per item id
+	associations map[uint32][]int
+}
+
+// heifParseMeta reads the items and their associated properties from the meta box.
+// The whole meta box has to be available before it is parsed.
+func heifParseMeta(p []byte, meta isobmffBox) (Result, heifMeta) {
+	// meta is a full box, skip version and flags
+	metaStart := meta.dataStart + 4
+
+	heif := heifMeta{
+		itemTypes:    make(map[uint32]string),
+		derivedFrom:  make(map[uint32][]uint32),
+		auxiliaryFor: make(map[uint32][]uint32),
+		locations:    make(map[uint32]heifItemLocation),
+		associations: make(map[uint32][]int),
+	}
+
+	// Primary item
+	result, pitm := isobmffFindBox(p, metaStart, meta.end, "pitm")
+	if result != Valid {
+		return Invalid, heifMeta{}
+	}
+
+	if pitm.end < pitm.dataStart+6 {
+		return Invalid, heifMeta{}
+	}
+
+	if p[pitm.dataStart] == 0 {
+		heif.primaryItemID = uint32(binary.BigEndian.Uint16(p[pitm.dataStart+4:]))
+	} else {
+		if pitm.end < pitm.dataStart+8 {
+			return Invalid, heifMeta{}
+		}
+		heif.primaryItemID = binary.BigEndian.Uint32(p[pitm.dataStart+4:])
+	}
+
+	// Item information, item references, item locations and item data are only
+	// needed to resolve derived images, so they are optional
+	if result, iinf := isobmffFindBox(p, metaStart, meta.end, "iinf"); result == Valid {
+		if !heifParseItemInfos(p, iinf, heif.itemTypes) {
+			return Invalid, heifMeta{}
+		}
+	}
+
+	if result, iref := isobmffFindBox(p, metaStart, meta.end, "iref"); result == Valid {
+		if !heifParseItemReferences(p, iref, heif.derivedFrom, heif.auxiliaryFor) {
+			return Invalid, heifMeta{}
+		}
+	}
+
+	if result, iloc := isobmffFindBox(p, metaStart, meta.end, "iloc"); result == Valid {
+		if !heifParseItemLocations(p[iloc.dataStart:iloc.end], heif.locations) {
+			return Invalid, heifMeta{}
+		}
+	}
+
+	if result, idat := isobmffFindBox(p, metaStart, meta.end, "idat"); result == Valid {
+		heif.idat = idat
+	}
+
+	// Item properties
+	result, iprp := isobmffFindBox(p, metaStart, meta.end, "iprp")
+	if result != Valid {
+		return Invalid, heifMeta{}
+	}
+
+	result, ipco := isobmffFindBox(p, iprp.dataStart, iprp.end, "ipco")
+	if result != Valid {
+		return Invalid, heifMeta{}
+	}
+
+	result, heif.properties = isobmffChildren(p, ipco.dataStart, ipco.end)
+	if result != Valid {
+		return Invalid, heifMeta{}
+	}
+
+	// Item property associations, there might be more than one ipma box
+	for i := iprp.dataStart; i < iprp.end; {
+		result, ipma := isobmffFindBox(p, i, iprp.end, "ipma")
+		if result != Valid {
+			break
+		}
+
+		if !heifParseAssociations(p[ipma.dataStart:ipma.end], heif.associations) {
+			return Invalid, heifMeta{}
+		}
+
+		i = ipma.end
+	}
+
+	return Valid, heif
+}
+
+// heifParseItemInfos reads the item type of every infe box inside of the iinf box.
+func heifParseItemInfos(p []byte, iinf isobmffBox, itemTypes map[uint32]string) bool {
+	if iinf.end < iinf.dataStart+6 {
+		return false
+	}
+
+	// Skip version, flags and entry count
+	i := iinf.dataStart + 4 + 2
+	if p[iinf.dataStart] != 0 {
+		i += 2
+	}
+
+	if i > iinf.end {
+		return false
+	}
+
+	result, boxes := isobmffChildren(p, i, iinf.end)
+	if result != Valid {
+		return false
+	}
+
+	for _, infe := range boxes {
+		if infe.boxType != "infe" || infe.end < infe.dataStart+4 {
+			continue
+		}
+
+		// Versions 0 and 1 do not have an item type
+		version := p[infe.dataStart]
+		j := infe.dataStart + 4
+
+		var itemID uint32
+		switch version {
+		case 2:
+			if infe.end < j+8 {
+				return false
+			}
+			itemID = uint32(binary.BigEndian.Uint16(p[j:]))
+			j += 2
+		case 3:
+			if infe.end < j+10 {
+				return false
+			}
+			itemID = binary.BigEndian.Uint32(p[j:])
+			j += 4
+		default:
+			continue
+		}
+
+		// Skip item protection index
+		j += 2
+
+		itemTypes[itemID] = string(p[j : j+4])
+	}
+
+	return true
+}
+
+// heifParseItemReferences reads the derived image and auxiliary image references of
+// the iref box.
+func heifParseItemReferences(p []byte, iref isobmffBox, derivedFrom map[uint32][]uint32, auxiliaryFor map[uint32][]uint32) bool {
+	if iref.end < iref.dataStart+4 {
+		return false
+	}
+
+	idSize := 2
+	if p[iref.dataStart] != 0 {
+		idSize = 4
+	}
+
+	result, boxes := isobmffChildren(p, iref.dataStart+4, iref.end)
+	if result != Valid {
+		return false
+	}
+
+	readID := func(i int) uint32 {
+		if idSize == 2 {
+			return uint32(binary.BigEndian.Uint16(p[i:]))
+		}
+		return binary.BigEndian.Uint32(p[i:])
+	}
+
+	for _, reference := range boxes {
+		references := derivedFrom
+		switch reference.boxType {
+		case "dimg":
+		case "auxl":
+			references = auxiliaryFor
+		default:
+			continue
+		}
+
+		i := reference.dataStart
+		if reference.end < i+idSize+2 {
+			return false
+		}
+
+		fromItemID := readID(i)
+		referenceCount := int(binary.BigEndian.Uint16(p[i+idSize:]))
+		i += idSize + 2
+
+		if reference.end < i+referenceCount*idSize {
+			return false
+		}
+
+		for j := 0; j < referenceCount; j++ {
+			references[fromItemID] = append(references[fromItemID], readID(i))
+			i += idSize
+		}
+	}
+
+	return true
+}
+
+// heifParseItemLocations reads the payload of an iloc box. Only the first extent
+// of each item is kept.
+func heifParseItemLocations(p []byte, locations map[uint32]heifItemLocation) bool {
+	if len(p) < 8 {
+		return false
+	}
+
+	version := p[0]
+	offsetSize := int(p[4] >> 4)
+	lengthSize := int(p[4] & 0xf)
+	baseOffsetSize := int(p[5] >> 4)
+	indexSize := 0
+	if version == 1 || version == 2 {
+		indexSize = int(p[5] & 0xf)
+	}
+
+	i := 6
+
+	var itemCount int
+	if version < 2 {
+		itemCount = int(binary.BigEndian.Uint16(p[i:]))
+		i += 2
+	} else {
+		if len(p) < i+4 {
+			return false
+		}
+		itemCount = int(binary.BigEndian.Uint32(p[i:]))
+		i += 4
+	}
+
+	for j := 0; j < itemCount; j++ {
+		var itemID uint32
+		if version < 2 {
+			if len(p) < i+2 {
+				return false
+			}
+			itemID = uint32(binary.BigEndian.Uint16(p[i:]))
+			i += 2
+		} else {
+			if len(p) < i+4 {
+				return false
+			}
+			itemID = binary.BigEndian.Uint32(p[i:])
+			i += 4
+		}
+
+		location := heifItemLocation{}
+
+		if version == 1 || version == 2 {
+			if len(p) < i+2 {
+				return false
+			}
+			location.constructionMethod = int(binary.BigEndian.Uint16(p[i:]) & 0xf)
+			i += 2
+		}
+
+		// Data reference index
+		i += 2
+
+		if len(p) < i+baseOffsetSize+2 {
+			return false
+		}
+
+		baseOffset := heifReadUint(p[i:], baseOffsetSize)
+		i += baseOffsetSize
+
+		extentCount := int(binary.BigEndian.Uint16(p[i:]))
+		i += 2
+
+		extentSize := indexSize + offsetSize + lengthSize
+		if len(p) < i+extentCount*extentSize {
+			return false
+		}
+
+		if extentCount > 0 {
+			location.offset = int(baseOffset + heifReadUint(p[i+indexSize:], offsetSize))
+			location.length = int(heifReadUint(p[i+indexSize+offsetSize:], lengthSize))
+			locations[itemID] = location
+		}
+
+		i += extentCount * extentSize
+	}
+
+	return true
+}
+
+// heifReadUint reads a big endian integer with a size of 0, 4 or 8 bytes.
+func heifReadUint(p []byte, size int) uint64 {
+	switch size {
+	case 4:
+		return uint64(binary.BigEndian.Uint32(p))
+	case 8:
+		return binary.BigEndian.Uint64(p)
+	default:
+		return 0
+	}
+}
+
+// heifParseAssociations reads the payload of an ipma box.
+func heifParseAssociations(p []byte, associations map[uint32][]int) bool {
+	if len(p) < 8 {
+		return false
+	}
+
+	version := p[0]
+	flags := uint32(p[1])<<16 | uint32(p[2])<<8 | uint32(p[3])
+	entryCount := binary.BigEndian.Uint32(p[4:])
+
+	i := 8
+	for j := uint32(0); j < entryCount; j++ {
+		var itemID uint32
+		if version < 1 {
+			if len(p) < i+3 {
+				return false
+			}
+			itemID = uint32(binary.BigEndian.Uint16(p[i:]))
+			i += 2
+		} else {
+			if len(p) < i+5 {
+				return false
+			}
+			itemID = binary.BigEndian.Uint32(p[i:])
+			i += 4
+		}
+
+		associationCount := int(p[i])
+		i++
+
+		for k := 0; k < associationCount; k++ {
+			var index int
+			if flags&1 == 1 {
+				if len(p) < i+2 {
+					return false
+				}
+				index = int(binary.BigEndian.Uint16(p[i:]) & 0x7fff)
+				i += 2
+			} else {
+				if len(p) < i+1 {
+					return false
+				}
+				index = int(p[i] & 0x7f)
+				i++
+			}
+
+			// Index 0 means no property is associated
+			if index == 0 {
+				continue
+			}
+
+			associations[itemID] = append(associations[itemID], index-1)
+		}
+	}
+
+	return true
+}
+
+// itemProperty returns the first property of the given type associated with an item.
+func (h heifMeta) itemProperty(itemID uint32, boxType string) (isobmffBox, bool) {
+	for _, index := range h.associations[itemID] {
+		if index >= len(h.properties) {
+			continue
+		}
+
+		if h.properties[index].boxType == boxType {
+			return h.properties[index], true
+		}
+	}
+
+	return isobmffBox{}, false
+}
+
+// itemData returns the data of an item, as long as it is stored in a single extent.
+func (h heifMeta) itemData(p []byte, itemID uint32) (Result, []byte) {
+	location, ok := h.locations[itemID]
+	if !ok {
+		return Invalid, nil
+	}
+
+	start := location.offset
+
+	switch location.constructionMethod {
+	case 0:
+		// Offset is relative to the start of the file
+	case 1:
+		// Offset is relative to the payload of the idat box
+		if h.idat.boxType == "" {
+			return Invalid, nil
+		}
+		start += h.idat.dataStart
+	default:
+		return Invalid, nil
+	}
+
+	end := start + location.length
+	if start < 0 || end < start {
+		return Invalid, nil
+	}
+
+	if len(p) < end {
+		return NeedMoreData, nil
+	}
+
+	return Valid, p[start:end]
+}
+
+// itemDataAt returns the data of an item like itemData, but reads data stored outside
+// of p directly from r. Items larger than maxLength are not read.
+func (h heifMeta) itemDataAt(p []byte, r io.ReaderAt, size int64, itemID uint32, maxLength int) (Result, []byte) {
+	location, ok := h.locations[itemID]
+	if !ok || location.constructionMethod != 0 {
+		return h.itemData(p, itemID)
+	}
+
+	start := int64(location.offset)
+	end := start + int64(location.length)
+	if start < 0 || end < start || location.length > maxLength || size < end {
+		return Invalid, nil
+	}
+
+	if int64(len(p)) >= end {
+		return Valid, p[start:end]
+	}
+
+	data := make([]byte, location.length)
+	if _, err := r.ReadAt(data, start); err != nil && err != io.EOF {
+		return Invalid, nil
+	}
+
+	return Valid, data
+}
+
+// Derived images can reference other derived images, limit the depth to avoid loops
+const heifMaxDerivationDepth = 8
+
+// itemSize returns the size of an item. The ispe property is preferred, derived
+// images without it are resolved by reading the grid or overlay description or by
+// following the reference of an identity transformation.
+func (h heifMeta) itemSize(p []byte, itemID uint32, depth int) (Result, ImageSize) {
+	if depth > heifMaxDerivationDepth {
+		return Invalid, ImageSize{}
+	}
+
+	ispe, ok := h.itemProperty(itemID, "ispe")
+	if ok && ispe.end >= ispe.dataStart+12 {
+		width := binary.BigEndian.Uint32(p[ispe.dataStart+4:])
+		height := binary.BigEndian.Uint32(p[ispe.dataStart+8:])
+		return Valid, ImageSize{Width: width, Height: height}
+	}
+
+	switch h.itemTypes[itemID] {
+	case "grid", "iovl":
+		result, data := h.itemData(p, itemID)
+		if result != Valid {
+			return result, ImageSize{}
+		}
+
+		// Grids store the number of rows and columns, overlays the fill color
+		// in front of the output size
+		i := 4
+		if h.itemTypes[itemID] == "iovl" {
+			i = 10
+		}
+
+		// Flag 1 signals 32 bit fields
+		if len(data) >= 2 && data[1]&1 == 1 {
+			if len(data) < i+8 {
+				return Invalid, ImageSize{}
+			}
+			width := binary.BigEndian.Uint32(data[i:])
+			height := binary.BigEndian.Uint32(data[i+4:])
+			return Valid, ImageSize{Width: width, Height: height}
+		}
+
+		if len(data) < i+4 {
+			return Invalid, ImageSize{}
+		}
+		width := uint32(binary.BigEndian.Uint16(data[i:]))
+		height := uint32(binary.BigEndian.Uint16(data[i+2:]))
+		return Valid, ImageSize{Width: width, Height: height}
+
+	case "iden":
+		sources := h.derivedFrom[itemID]
+		if len(sources) == 0 {
+			return Invalid, ImageSize{}
+		}
+
+		return h.itemSize(p, sources[0], depth+1)
+	}
+
+	return Invalid, ImageSize{}
+}
+
+func init() {
+	register(&HEICParser{})
+	register(&HEIFParser{})
+}
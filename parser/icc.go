@@ -0,0 +1,165 @@
+package parser
+
+import (
+	"bytes"
+	"encoding/binary"
+	"strings"
+	"unicode/utf16"
+)
+
+// Information about the icc profile structure can be found here:
+// https://www.color.org/specification/ICC.1-2022-05.pdf
+// https://www.color.org/ICC_Minor_Revision_for_Web.pdf
+
+// Chromaticity coordinates of the CIE 1931 xy color space
+type Chromaticity struct {
+	X float64
+	Y float64
+}
+
+// Coding-independent code points as defined by ITU-T H.273, e.g. primaries 1 and
+// transfer characteristics 13 for sRGB or primaries 12 for Display P3
+type CICP struct {
+	ColorPrimaries          uint16
+	TransferCharacteristics uint16
+	MatrixCoefficients      uint16
+	FullRange               bool
+}
+
+type ColorProfile struct {
+	// Embedded ICC profile, nil if the image does not contain one
+	ICC []byte
+
+	// Description (e.g. Display P3, Adobe RGB (1998)) and data color space (e.g. RGB,
+	// GRAY, CMYK) of the ICC profile, empty if the profile can not be parsed
+	Description string
+	ColorSpace  string
+
+	// PNG files declaring the sRGB color space by the sRGB chunk, with the rendering
+	// intent of the chunk
+	SRGB            bool
+	RenderingIntent uint8
+
+	// Gamma of the PNG gAMA chunk, e.g. 0.45455 for a display gamma of 2.2, 0 if the
+	// chunk is missing
+	Gamma float64
+
+	// White point and primaries of the PNG cHRM chunk. Only valid if
+	// HasChromaticities is set.
+	HasChromaticities bool
+	WhitePoint        Chromaticity
+	Red               Chromaticity
+	Green             Chromaticity
+	Blue              Chromaticity
+
+	// Code points of the PNG cICP chunk or the nclx color box of HEIF files. Only
+	// valid if HasCICP is set.
+	HasCICP bool
+	CICP    CICP
+}
+
+// Implemented by parsers of image formats which can contain an ICC profile or other
+// color information. Images without color information are reported as Valid with an
+// empty profile.
+type ColorProfileParser interface {
+	GetColorProfile(p []byte) (Result, ColorProfile)
+}
+
+// iccColorProfile returns a color profile holding the given ICC profile, with the
+// description and color space as far as they can be read.
+func iccColorProfile(profile []byte) ColorProfile {
+	colorProfile := ColorProfile{ICC: profile}
+
+	// Profile size, preferred CMM type, version, class, color space and the profile
+	// file signature at offset 36
+	if len(profile) < 132 || string(profile[36:40]) != "acsp" {
+		return colorProfile
+	}
+
+	colorProfile.ColorSpace = strings.TrimRight(string(profile[16:20]), " ")
+
+	// Tag table with signature, offset and size of each tag
+	tagCount := int(binary.BigEndian.Uint32(profile[128:]))
+	if tagCount < 0 || len(profile) < 132+12*tagCount {
+		return colorProfile
+	}
+
+	for i := 0; i < tagCount; i++ {
+		entry := profile[132+12*i:]
+		if string(entry[:4]) != "desc" {
+			continue
+		}
+
+		offset := int(binary.BigEndian.Uint32(entry[4:]))
+		size := int(binary.BigEndian.Uint32(entry[8:]))
+		if offset < 0 || size < 0 || offset+size < offset || len(profile) < offset+size {
+			return colorProfile
+		}
+
+		colorProfile.Description = iccDecodeText(profile[offset : offset+size])
+		break
+	}
+
+	return colorProfile
+}
+
+// iccDecodeText decodes tags of the textDescriptionType of version 2 profiles and of
+// the multiLocalizedUnicodeType of version 4 profiles. English is preferred over the
+// first record of localized texts.
+func iccDecodeText(data []byte) string {
+	if len(data) < 12 {
+		return ""
+	}
+
+	switch string(data[:4]) {
+	case "desc":
+		// Reserved bytes and the length of the ASCII text including its terminator
+		length := int(binary.BigEndian.Uint32(data[8:]))
+		if length < 0 || len(data) < 12+length {
+			return ""
+		}
+
+		text := data[12 : 12+length]
+		if end := bytes.IndexByte(text, 0); end != -1 {
+			text = text[:end]
+		}
+
+		return string(text)
+	case "mluc":
+		// Reserved bytes, number of records and the size of each record
+		if len(data) < 16 {
+			return ""
+		}
+
+		recordCount := int(binary.BigEndian.Uint32(data[8:]))
+		recordSize := int(binary.BigEndian.Uint32(data[12:]))
+		if recordCount < 1 || recordSize < 12 || len(data) < 16+recordCount*recordSize {
+			return ""
+		}
+
+		record := data[16:]
+		for i := 0; i < recordCount; i++ {
+			// Language and country code, length and offset of the text
+			if string(data[16+i*recordSize:16+i*recordSize+2]) == "en" {
+				record = data[16+i*recordSize:]
+				break
+			}
+		}
+
+		length := int(binary.BigEndian.Uint32(record[4:]))
+		offset := int(binary.BigEndian.Uint32(record[8:]))
+		if length < 0 || offset < 0 || offset+length < offset || len(data) < offset+length {
+			return ""
+		}
+
+		text := data[offset : offset+length]
+		runes := make([]uint16, 0, len(text)/2)
+		for j := 0; j+1 < len(text); j += 2 {
+			runes = append(runes, binary.BigEndian.Uint16(text[j:]))
+		}
+
+		return strings.TrimRight(string(utf16.Decode(runes)), "\x00")
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,250 @@
+package parser
+
+import (
+	"bytes"
+	"encoding/binary"
+)
+
+// https://en.wikipedia.org/wiki/ICO_(file_format)
+// https://learn.microsoft.com/en-us/previous-versions/ms997538(v=msdn.10)
+
+type ICOParser struct{}
+
+type CURParser struct{}
+
+type ICOEntry struct {
+	// Size stored in the directory entry, where 0 is interpreted as 256
+	Size ImageSize
+
+	// Number of colors in the palette, 0 if no palette is used
+	ColorCount uint8
+
+	// Color planes and bits per pixel, only used by icons
+	Planes   uint16
+	BitCount uint16
+
+	// Hotspot coordinates, only used by cursors
+	HotspotX uint16
+	HotspotY uint16
+
+	// Size and offset of the image data
+	DataSize   uint32
+	DataOffset uint32
+
+	// Image data is a PNG file instead of a BMP without file header
+	PNG bool
+
+	// Size stored in the IHDR chunk of the embedded PNG file
+	PNGSize ImageSize
+}
+
+const (
+	icoTypeIcon   = 1
+	icoTypeCursor = 2
+)
+
+func (I ICOParser) Type() ImageType {
+	return ICO
+}
+
+func (I ICOParser) DetectType(p []byte) (r Result) {
+	return icoDetectType(p, icoTypeIcon)
+}
+
+func (I ICOParser) GetSize(p []byte) (r Result, t ImageSize) {
+	return icoGetSize(p, icoTypeIcon)
+}
+
+// GetEntries returns all images contained in the icon.
+func (I ICOParser) GetEntries(p []byte) (r Result, e []ICOEntry) {
+	return icoGetEntries(p, icoTypeIcon)
+}
+
+func (I ICOParser) GetPixelFormat(p []byte) (r Result, f PixelFormat) {
+	return icoGetPixelFormat(p, icoTypeIcon)
+}
+
+func (C CURParser) Type() ImageType {
+	return CUR
+}
+
+func (C CURParser) DetectType(p []byte) (r Result) {
+	return icoDetectType(p, icoTypeCursor)
+}
+
+func (C CURParser) GetSize(p []byte) (r Result, t ImageSize) {
+	return icoGetSize(p, icoTypeCursor)
+}
+
+// GetEntries returns all images contained in the cursor.
+func (C CURParser) GetEntries(p []byte) (r Result, e []ICOEntry) {
+	return icoGetEntries(p, icoTypeCursor)
+}
+
+func (C CURParser) GetPixelFormat(p []byte) (r Result, f PixelFormat) {
+	return icoGetPixelFormat(p, icoTypeCursor)
+}
+
+func icoDetectType(p []byte, iconType uint16) Result {
+	// ICONDIR and the first ICONDIRENTRY
+	if len(p) < 6+16 {
+		return NeedMoreData
+	}
+
+	// Reserved, always zero
+	if p[0] != 0 || p[1] != 0 {
+		return Invalid
+	}
+
+	if binary.LittleEndian.Uint16(p[2:]) != iconType {
+		return Invalid
+	}
+
+	count := int(binary.LittleEndian.Uint16(p[4:]))
+	if count == 0 {
+		return Invalid
+	}
+
+	// The header is quite weak, so check the first entry as well
+	entry := p[6:]
+
+	// Reserved, should be zero but some encoders write 255
+	if entry[3] != 0 && entry[3] != 255 {
+		return Invalid
+	}
+
+	// Color planes should be 0 or 1
+	if iconType == icoTypeIcon && binary.LittleEndian.Uint16(entry[4:]) > 1 {
+		return Invalid
+	}
+
+	// Image data can not overlap with the directory
+	if int(binary.LittleEndian.Uint32(entry[12:])) < 6+16*count {
+		return Invalid
+	}
+
+	return Valid
+}
+
+func icoGetEntries(p []byte, iconType uint16) (Result, []ICOEntry) {
+	if result := icoDetectType(p, iconType); result != Valid {
+		return result, nil
+	}
+
+	count := int(binary.LittleEndian.Uint16(p[4:]))
+
+	if len(p) < 6+16*count {
+		return NeedMoreData, nil
+	}
+
+	pngFileSignature := []byte{'\x89', 'P', 'N', 'G', '\x0D', '\x0A', '\x1A', '\x0A'}
+
+	entries := make([]ICOEntry, count)
+
+	for j := 0; j < count; j++ {
+		i := 6 + 16*j
+
+		entry := ICOEntry{
+			Size:       ImageSize{Width: uint32(p[i]), Height: uint32(p[i+1])},
+			ColorCount: p[i+2],
+			DataSize:   binary.LittleEndian.Uint32(p[i+8:]),
+			DataOffset: binary.LittleEndian.Uint32(p[i+12:]),
+		}
+
+		if entry.Size.Width == 0 {
+			entry.Size.Width = 256
+		}
+
+		if entry.Size.Height == 0 {
+			entry.Size.Height = 256
+		}
+
+		if iconType == icoTypeIcon {
+			entry.Planes = binary.LittleEndian.Uint16(p[i+4:])
+			entry.BitCount = binary.LittleEndian.Uint16(p[i+6:])
+		} else {
+			entry.HotspotX = binary.LittleEndian.Uint16(p[i+4:])
+			entry.HotspotY = binary.LittleEndian.Uint16(p[i+6:])
+		}
+
+		// PNG signature and the IHDR chunk holding the size
+		offset := int(entry.DataOffset)
+		if offset < 0 || len(p) < offset+24 {
+			return NeedMoreData, nil
+		}
+
+		if bytes.Equal(p[offset:offset+len(pngFileSignature)], pngFileSignature) {
+			entry.PNG = true
+			entry.PNGSize.Width = binary.BigEndian.Uint32(p[offset+16:])
+			entry.PNGSize.Height = binary.BigEndian.Uint32(p[offset+20:])
+		}
+
+		entries[j] = entry
+	}
+
+	return Valid, entries
+}
+
+// icoGetSize returns the size of the largest image.
+func icoGetSize(p []byte, iconType uint16) (Result, ImageSize) {
+	result, entries := icoGetEntries(p, iconType)
+	if result != Valid {
+		return result, ImageSize{}
+	}
+
+	return Valid, icoEntrySize(entries[icoLargestEntry(entries)])
+}
+
+// icoGetPixelFormat returns the pixel format of the largest image. Bitmaps with 32
+// bits per pixel use the fourth byte as alpha channel, all other bitmaps only have
+// the transparency mask.
+func icoGetPixelFormat(p []byte, iconType uint16) (Result, PixelFormat) {
+	result, entries := icoGetEntries(p, iconType)
+	if result != Valid {
+		return result, PixelFormat{}
+	}
+
+	entry := entries[icoLargestEntry(entries)]
+	offset := int(entry.DataOffset)
+
+	if entry.PNG {
+		return pngGetPixelFormat(p[offset:])
+	}
+
+	// biBitCount of the BITMAPINFOHEADER
+	if len(p) < offset+16 {
+		return NeedMoreData, PixelFormat{}
+	}
+
+	bitCount := binary.LittleEndian.Uint16(p[offset+14:])
+
+	return bmpPixelFormat(bitCount, bitCount == 32)
+}
+
+// icoLargestEntry returns the index of the first entry with the largest area.
+func icoLargestEntry(entries []ICOEntry) int {
+	largest := 0
+
+	for j, entry := range entries {
+		size, largestSize := icoEntrySize(entry), icoEntrySize(entries[largest])
+		if uint64(size.Width)*uint64(size.Height) > uint64(largestSize.Width)*uint64(largestSize.Height) {
+			largest = j
+		}
+	}
+
+	return largest
+}
+
+// icoEntrySize returns the size of the PNG file or the size of the directory entry.
+func icoEntrySize(entry ICOEntry) ImageSize {
+	if entry.PNG {
+		return entry.PNGSize
+	}
+
+	return entry.Size
+}
+
+func init() {
+	register(&ICOParser{})
+	register(&CURParser{})
+}
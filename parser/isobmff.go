@@ -0,0 +1,398 @@
+package parser
+
+import (
+	"encoding/binary"
+	"time"
+)
+
+// Information about the ISO base media file format (ISO/IEC 14496-12) and the
+// HEIF image items stored inside of it (ISO/IEC 23008-12) can be found here:
+// https://en.wikipedia.org/wiki/ISO_base_media_file_format
+// https://aomediacodec.github.io/av1-avif/
+
+type isobmffBox struct {
+	boxType string
+
+	// Offset of the box header
+	start int
+
+	// Offset of the box payload
+	dataStart int
+
+	// Offset right after the box, -1 if the box extends to the end of the file
+	end int
+}
+
+// isobmffReadBox reads the header of the box located at offset i.
+func isobmffReadBox(p []byte, i int) (Result, isobmffBox) {
+	if len(p) < i+8 {
+		return NeedMoreData, isobmffBox{}
+	}
+
+	size := uint64(binary.BigEndian.Uint32(p[i:]))
+	box := isobmffBox{boxType: string(p[i+4 : i+8]), start: i, dataStart: i + 8}
+
+	switch size {
+	case 0:
+		// Box extends to the end of the file
+		box.end = -1
+		return Valid, box
+	case 1:
+		// 64 bit largesize follows the box type
+		if len(p) < i+16 {
+			return NeedMoreData, isobmffBox{}
+		}
+
+		size = binary.BigEndian.Uint64(p[i+8:])
+		box.dataStart = i + 16
+	}
+
+	if size < uint64(box.dataStart-i) || int(size) < 0 || i+int(size) < i {
+		return Invalid, isobmffBox{}
+	}
+
+	box.end = i + int(size)
+
+	return Valid, box
+}
+
+// isobmffFindBox searches the sibling boxes between start and end for the first box
+// of one of the given types. An end of -1 means the search is not limited by a
+// parent box.
+func isobmffFindBox(p []byte, start int, end int, boxTypes ...string) (Result, isobmffBox) {
+	i := start
+
+	for end == -1 || i < end {
+		if end != -1 && i+8 > end {
+			return Invalid, isobmffBox{}
+		}
+
+		result, box := isobmffReadBox(p, i)
+		if result != Valid {
+			return result, isobmffBox{}
+		}
+
+		// A box inside of a parent box can not extend to the end of the file
+		if end != -1 && (box.end == -1 || box.end > end) {
+			return Invalid, isobmffBox{}
+		}
+
+		for _, boxType := range boxTypes {
+			if box.boxType == boxType {
+				return Valid, box
+			}
+		}
+
+		// Last box of the file
+		if box.end == -1 {
+			return Invalid, isobmffBox{}
+		}
+
+		i = box.end
+	}
+
+	return Invalid, isobmffBox{}
+}
+
+// isobmffFindTopLevelBox searches the boxes following the ftyp box for the first
+// box of one of the given types and makes sure the whole box is available.
+func isobmffFindTopLevelBox(p []byte, boxTypes ...string) (Result, isobmffBox) {
+	result, ftyp := isobmffReadBox(p, 0)
+	if result != Valid {
+		return result, isobmffBox{}
+	}
+
+	if ftyp.end == -1 {
+		return Invalid, isobmffBox{}
+	}
+
+	result, box := isobmffFindBox(p, ftyp.end, -1, boxTypes...)
+	if result != Valid {
+		return result, isobmffBox{}
+	}
+
+	if box.end == -1 {
+		return Invalid, isobmffBox{}
+	}
+
+	if len(p) < box.end {
+		return NeedMoreData, isobmffBox{}
+	}
+
+	return Valid, box
+}
+
+// isobmffChildren returns all boxes between start and end.
+func isobmffChildren(p []byte, start int, end int) (Result, []isobmffBox) {
+	var boxes []isobmffBox
+
+	for i := start; i < end; {
+		if i+8 > end {
+			return Invalid, nil
+		}
+
+		result, box := isobmffReadBox(p, i)
+		if result != Valid {
+			return result, nil
+		}
+
+		if box.end == -1 || box.end > end {
+			return Invalid, nil
+		}
+
+		boxes = append(boxes, box)
+		i = box.end
+	}
+
+	return Valid, boxes
+}
+
+// isobmffBrands returns the major brand and the compatible brands of the ftyp box,
+// which has to be the first box of the file.
+func isobmffBrands(p []byte) (Result, string, []string) {
+	return isobmffBrandsAt(p, 0)
+}
+
+// isobmffBrandsAt returns the brands of the ftyp box located at offset i.
+func isobmffBrandsAt(p []byte, i int) (Result, string, []string) {
+	if len(p) < i+8 {
+		return NeedMoreData, "", nil
+	}
+
+	if string(p[i+4:i+8]) != "ftyp" {
+		return Invalid, "", nil
+	}
+
+	result, box := isobmffReadBox(p, i)
+	if result != Valid {
+		return result, "", nil
+	}
+
+	// Major brand and minor version are mandatory
+	if box.end == -1 || box.end < box.dataStart+8 {
+		return Invalid, "", nil
+	}
+
+	if len(p) < box.end {
+		return NeedMoreData, "", nil
+	}
+
+	majorBrand := string(p[box.dataStart : box.dataStart+4])
+
+	var compatibleBrands []string
+	for j := box.dataStart + 8; j+4 <= box.end; j += 4 {
+		compatibleBrands = append(compatibleBrands, string(p[j:j+4]))
+	}
+
+	return Valid, majorBrand, compatibleBrands
+}
+
+// isobmffImageType decides which image type a file based on the ISO base media file
+// format belongs to. Specific brands take precedence over the generic HEIF brands,
+// since files often list them next to each other in any order.
+func isobmffImageType(p []byte) (Result, ImageType) {
+	result, majorBrand, compatibleBrands := isobmffBrands(p)
+	if result != Valid {
+		return result, UnknownType
+	}
+
+	brands := append([]string{majorBrand}, compatibleBrands...)
+
+	for _, brand := range brands {
+		if imageType := isobmffBrandImageType(brand); imageType != UnknownType {
+			return Valid, imageType
+		}
+	}
+
+	for _, brand := range brands {
+		switch brand {
+		case "mif1", "msf1":
+			return Valid, HEIF
+		}
+	}
+
+	return Valid, UnknownType
+}
+
+func isobmffBrandImageType(brand string) ImageType {
+	switch brand {
+	case "avif", "avis":
+		return AVIF
+	case "heic", "heix", "heim", "heis", "hevc", "hevx":
+		return HEIC
+	case "crx ":
+		return CR3
+	default:
+		return UnknownType
+	}
+}
+
+// isobmffTrackSize returns the size of the first track of a moov box, which is how
+// image sequences without a primary image item describe their dimensions.
+func isobmffTrackSize(p []byte, moov isobmffBox) (Result, ImageSize) {
+	result, trak := isobmffFindBox(p, moov.dataStart, moov.end, "trak")
+	if result != Valid {
+		return Invalid, ImageSize{}
+	}
+
+	result, tkhd := isobmffFindBox(p, trak.dataStart, trak.end, "tkhd")
+	if result != Valid {
+		return Invalid, ImageSize{}
+	}
+
+	// Width and height follow the times, track id, duration, layer, volume and
+	// matrix fields, whose size depends on the box version
+	i := tkhd.dataStart + 4 + 72
+	if tkhd.end > tkhd.dataStart && p[tkhd.dataStart] == 1 {
+		i += 12
+	}
+
+	if tkhd.end < i+8 {
+		return Invalid, ImageSize{}
+	}
+
+	// Width and height are stored as 16.16 fixed point numbers
+	width := binary.BigEndian.Uint32(p[i:]) >> 16
+	height := binary.BigEndian.Uint32(p[i+4:]) >> 16
+
+	return Valid, ImageSize{Width: width, Height: height}
+}
+
+// isobmffGetAnimation reads the sample timing of the first picture or video track of
+// image sequences, which are announced by their brands. Files without one of these
+// brands are still images. The sequence loops forever if its edit list is repeated.
+func isobmffGetAnimation(p []byte, maxFrames int) (Result, Animation) {
+	result, majorBrand, compatibleBrands := isobmffBrands(p)
+	if result != Valid {
+		return result, Animation{}
+	}
+
+	sequence := false
+	for _, brand := range append([]string{majorBrand}, compatibleBrands...) {
+		switch brand {
+		case "avis", "hevs", "msf1":
+			sequence = true
+		}
+	}
+
+	if !sequence {
+		return Valid, Animation{Frames: 1}
+	}
+
+	result, moov := isobmffFindTopLevelBox(p, "moov")
+	if result != Valid {
+		return result, Animation{}
+	}
+
+	result, boxes := isobmffChildren(p, moov.dataStart, moov.end)
+	if result != Valid {
+		return Invalid, Animation{}
+	}
+
+	for _, trak := range boxes {
+		if trak.boxType != "trak" {
+			continue
+		}
+
+		result, mdia := isobmffFindBox(p, trak.dataStart, trak.end, "mdia")
+		if result != Valid {
+			continue
+		}
+
+		// Handler type follows version, flags and a predefined field
+		result, hdlr := isobmffFindBox(p, mdia.dataStart, mdia.end, "hdlr")
+		if result != Valid || hdlr.end < hdlr.dataStart+12 {
+			continue
+		}
+
+		if handler := string(p[hdlr.dataStart+8 : hdlr.dataStart+12]); handler != "pict" && handler != "vide" {
+			continue
+		}
+
+		return isobmffTrackAnimation(p, trak, mdia, maxFrames)
+	}
+
+	return Invalid, Animation{}
+}
+
+// isobmffTrackAnimation counts the samples of a track, using the time scale of the
+// media header and the sample durations of the decoding time to sample box.
+func isobmffTrackAnimation(p []byte, trak isobmffBox, mdia isobmffBox, maxFrames int) (Result, Animation) {
+	// Time scale follows the creation and modification times
+	result, mdhd := isobmffFindBox(p, mdia.dataStart, mdia.end, "mdhd")
+	if result != Valid || mdhd.end < mdhd.dataStart+4 {
+		return Invalid, Animation{}
+	}
+
+	i := mdhd.dataStart + 4 + 8
+	if p[mdhd.dataStart] == 1 {
+		i += 8
+	}
+
+	if mdhd.end < i+4 {
+		return Invalid, Animation{}
+	}
+
+	timeScale := uint64(binary.BigEndian.Uint32(p[i:]))
+	if timeScale == 0 {
+		return Invalid, Animation{}
+	}
+
+	result, minf := isobmffFindBox(p, mdia.dataStart, mdia.end, "minf")
+	if result != Valid {
+		return Invalid, Animation{}
+	}
+
+	result, stbl := isobmffFindBox(p, minf.dataStart, minf.end, "stbl")
+	if result != Valid {
+		return Invalid, Animation{}
+	}
+
+	result, stts := isobmffFindBox(p, stbl.dataStart, stbl.end, "stts")
+	if result != Valid || stts.end < stts.dataStart+8 {
+		return Invalid, Animation{}
+	}
+
+	animation := Animation{Loops: 1}
+
+	// Flag 1 of the edit list repeats the edits
+	if result, edts := isobmffFindBox(p, trak.dataStart, trak.end, "edts"); result == Valid {
+		if result, elst := isobmffFindBox(p, edts.dataStart, edts.end, "elst"); result == Valid && elst.end >= elst.dataStart+4 {
+			if p[elst.dataStart+3]&0x01 == 1 {
+				animation.Loops = 0
+			}
+		}
+	}
+
+	// Runs of samples with the same duration
+	entryCount := int(binary.BigEndian.Uint32(p[stts.dataStart+4:]))
+	if entryCount < 0 || stts.end < stts.dataStart+8+8*entryCount {
+		return Invalid, Animation{}
+	}
+
+	var ticks uint64
+
+	for j := 0; j < entryCount && !animation.Truncated; j++ {
+		k := stts.dataStart + 8 + 8*j
+		sampleCount := int(binary.BigEndian.Uint32(p[k:]))
+		sampleDelta := uint64(binary.BigEndian.Uint32(p[k+4:]))
+
+		if maxFrames > 0 && animation.Frames+sampleCount > maxFrames {
+			sampleCount = maxFrames - animation.Frames
+			animation.Truncated = true
+		}
+
+		animation.Frames += sampleCount
+		ticks += uint64(sampleCount) * sampleDelta
+	}
+
+	animation.Duration = time.Duration(ticks/timeScale)*time.Second +
+		time.Duration(ticks%timeScale)*time.Second/time.Duration(timeScale)
+
+	animation.Animated = animation.Frames > 1 || animation.Truncated
+	if !animation.Animated {
+		animation.Loops = 0
+	}
+
+	return Valid, animation
+}
@@ -2,6 +2,7 @@ package parser
 
 import (
 	"bytes"
+	"encoding/binary"
 )
 
 // Information about the jpeg structure can be found here:
@@ -97,6 +98,441 @@ func (J JPEGParser) GetSize(p []byte) (r Result, t ImageSize) {
 	}
 }
 
+// GetPixelFormat reads the sample precision and the components of the frame header.
+// Three components are YCbCr, unless the Adobe APP14 segment or the component ids
+// declare them as RGB. Four components are CMYK, which Adobe might store as YCCK.
+func (J JPEGParser) GetPixelFormat(p []byte) (r Result, f PixelFormat) {
+	if result := J.DetectType(p); result != Valid {
+		return result, PixelFormat{}
+	}
+
+	// Color transform of the Adobe APP14 segment, -1 if there is none
+	transform := -1
+
+	for i := 2; ; {
+		result, segment := jpegNextSegment(p, i)
+		if result != Valid {
+			return result, PixelFormat{}
+		}
+
+		if segment.marker == '\xda' {
+			return Invalid, PixelFormat{}
+		}
+
+		// Identifier, version, two flag fields and the transform
+		if segment.marker == '\xee' && segment.end >= segment.dataStart+12 {
+			if len(p) < segment.dataStart+12 {
+				return NeedMoreData, PixelFormat{}
+			}
+
+			if string(p[segment.dataStart:segment.dataStart+5]) == "Adobe" {
+				transform = int(p[segment.dataStart+11])
+			}
+		}
+
+		if jpegIsFrameHeader(segment.marker) {
+			// Precision, height, width and the number of components, followed by
+			// three bytes per component starting with its id
+			start := segment.dataStart
+			if len(p) < start+6 {
+				return NeedMoreData, PixelFormat{}
+			}
+
+			components := int(p[start+5])
+			if len(p) < start+6+3*components {
+				return NeedMoreData, PixelFormat{}
+			}
+
+			pixelFormat := PixelFormat{BitDepth: p[start], Channels: uint16(components)}
+
+			switch components {
+			case 1:
+				pixelFormat.ColorModel = ColorModelGray
+			case 3:
+				pixelFormat.ColorModel = ColorModelYCbCr
+
+				ids := []byte{p[start+6], p[start+9], p[start+12]}
+				if transform == 0 || string(ids) == "RGB" {
+					pixelFormat.ColorModel = ColorModelRGB
+				}
+			case 4:
+				pixelFormat.ColorModel = ColorModelCMYK
+			}
+
+			return Valid, pixelFormat
+		}
+
+		i = segment.end
+	}
+}
+
+// GetOrientation reads the Orientation tag of the EXIF data stored in the APP1 segment.
+func (J JPEGParser) GetOrientation(p []byte) (r Result, o Orientation) {
+	if result := J.DetectType(p); result != Valid {
+		return result, OrientationNormal
+	}
+
+	result, data, complete := jpegEXIFData(p)
+	if result != Valid {
+		return result, OrientationNormal
+	}
+
+	if data == nil {
+		return Valid, OrientationNormal
+	}
+
+	result, orientation := exifOrientation(data, complete)
+	if result == NeedMoreData {
+		return NeedMoreData, OrientationNormal
+	}
+
+	return Valid, orientation
+}
+
+// GetResolution reads the pixel density of the JFIF APP0 segment. If it only
+// describes the aspect ratio of the pixels, the resolution tags of the EXIF data are
+// used instead. The aspect ratio is reported with ResolutionUnitNone only if the
+// EXIF data has no resolution.
+func (J JPEGParser) GetResolution(p []byte) (r Result, t Resolution) {
+	if result := J.DetectType(p); result != Valid {
+		return result, Resolution{}
+	}
+
+	jfifIdentifier := []byte("JFIF\x00")
+
+	jfif := Resolution{}
+
+	result, segment := jpegFindSegment(p, '\xe0', jfifIdentifier)
+	if result == NeedMoreData {
+		return NeedMoreData, Resolution{}
+	}
+
+	// Version (2), units (1), horizontal and vertical density (2 each)
+	if result == Valid && segment.end >= segment.dataStart+len(jfifIdentifier)+7 {
+		i := segment.dataStart + len(jfifIdentifier)
+		if len(p) < i+7 {
+			return NeedMoreData, Resolution{}
+		}
+
+		jfif.X = float64(binary.BigEndian.Uint16(p[i+3:]))
+		jfif.Y = float64(binary.BigEndian.Uint16(p[i+5:]))
+
+		switch p[i+2] {
+		case 1:
+			jfif.Unit = ResolutionUnitInch
+		case 2:
+			jfif.Unit = ResolutionUnitCentimeter
+		}
+
+		if jfif.X == 0 || jfif.Y == 0 {
+			jfif = Resolution{}
+		}
+
+		if jfif.Unit != ResolutionUnitNone {
+			return Valid, jfif
+		}
+	}
+
+	result, data, complete := jpegEXIFData(p)
+	if result != Valid {
+		return result, Resolution{}
+	}
+
+	if data != nil {
+		result, resolution := exifResolution(data, complete)
+		if result == NeedMoreData {
+			return NeedMoreData, Resolution{}
+		}
+
+		if result == Valid && resolution.X > 0 {
+			return Valid, resolution
+		}
+	}
+
+	return Valid, jfif
+}
+
+// GetColorProfile reassembles the ICC profile, which is split across APP2 segments
+// as a profile might not fit into a single segment. Each segment holds its sequence
+// number and the total number of segments.
+func (J JPEGParser) GetColorProfile(p []byte) (r Result, c ColorProfile) {
+	if result := J.DetectType(p); result != Valid {
+		return result, ColorProfile{}
+	}
+
+	iccIdentifier := []byte("ICC_PROFILE\x00")
+
+	result, segments := jpegFindSegments(p, '\xe2', iccIdentifier)
+	if result != Valid {
+		return result, ColorProfile{}
+	}
+
+	if len(segments) == 0 {
+		return Valid, ColorProfile{}
+	}
+
+	parts := make([][]byte, len(segments))
+
+	for _, segment := range segments {
+		data := p[segment.dataStart+len(iccIdentifier) : segment.end]
+		if len(data) < 2 {
+			return Invalid, ColorProfile{}
+		}
+
+		sequenceNumber, count := int(data[0]), int(data[1])
+		if count != len(segments) || sequenceNumber < 1 || sequenceNumber > count || parts[sequenceNumber-1] != nil {
+			return Invalid, ColorProfile{}
+		}
+
+		parts[sequenceNumber-1] = data[2:]
+	}
+
+	return Valid, iccColorProfile(bytes.Join(parts, nil))
+}
+
+// GetEXIF decodes the requested fields of the EXIF data stored in the APP1 segment.
+// The segment is decoded while it is read, so trailing data like the thumbnail is
+// not needed.
+func (J JPEGParser) GetEXIF(p []byte, fields EXIFFields) (r Result, e EXIF) {
+	if result := J.DetectType(p); result != Valid {
+		return result, EXIF{}
+	}
+
+	result, data, complete := jpegEXIFData(p)
+	if result != Valid {
+		return result, EXIF{}
+	}
+
+	if data == nil {
+		return Invalid, EXIF{}
+	}
+
+	return exifDecode(data, complete, fields)
+}
+
+// jpegEXIFData returns the EXIF data of the APP1 segment, as far as it is available,
+// and whether it is complete. Missing EXIF data is reported as Valid with nil data.
+func jpegEXIFData(p []byte) (Result, []byte, bool) {
+	exifIdentifier := []byte("Exif\x00\x00")
+
+	result, segment := jpegFindSegment(p, '\xe1', exifIdentifier)
+	if result == NeedMoreData {
+		return NeedMoreData, nil, false
+	}
+
+	if result != Valid {
+		return Valid, nil, false
+	}
+
+	start := segment.dataStart + len(exifIdentifier)
+
+	if len(p) < segment.end {
+		return Valid, p[start:], false
+	}
+
+	return Valid, p[start:segment.end], true
+}
+
+// GetXMP returns the XMP packet stored in the APP1 segment.
+func (J JPEGParser) GetXMP(p []byte) (r Result, x []byte) {
+	if result := J.DetectType(p); result != Valid {
+		return result, nil
+	}
+
+	xmpIdentifier := []byte("http://ns.adobe.com/xap/1.0/\x00")
+
+	result, segment := jpegFindSegment(p, '\xe1', xmpIdentifier)
+	if result != Valid {
+		return result, nil
+	}
+
+	if len(p) < segment.end {
+		return NeedMoreData, nil
+	}
+
+	return Valid, p[segment.dataStart+len(xmpIdentifier) : segment.end]
+}
+
+// GetExtendedXMP reassembles the extended XMP packet, which is split across multiple
+// APP1 segments as the standard packet is limited to the size of one segment. The
+// extended packet belongs to the standard packet via its xmpNote:HasExtendedXMP
+// property, which holds the GUID of the extended packet.
+func (J JPEGParser) GetExtendedXMP(p []byte) (r Result, x []byte) {
+	result, packet := J.GetXMP(p)
+	if result != Valid {
+		return result, nil
+	}
+
+	guid := xmpExtendedGUID(packet)
+	if guid == "" {
+		return Invalid, nil
+	}
+
+	extensionIdentifier := []byte("http://ns.adobe.com/xmp/extension/\x00")
+
+	result, segments := jpegFindSegments(p, '\xe1', extensionIdentifier)
+	if result != Valid {
+		return result, nil
+	}
+
+	var extended []byte
+	filled := 0
+
+	for _, segment := range segments {
+		// GUID, full length and offset of this part
+		data := p[segment.dataStart+len(extensionIdentifier) : segment.end]
+		if len(data) < 40 || string(data[:32]) != guid {
+			continue
+		}
+
+		fullLength := int(binary.BigEndian.Uint32(data[32:]))
+		offset := int(binary.BigEndian.Uint32(data[36:]))
+		part := data[40:]
+
+		if extended == nil {
+			// Every part but the last fills a whole segment
+			if fullLength > len(segments)*65535 {
+				return Invalid, nil
+			}
+			extended = make([]byte, fullLength)
+		}
+
+		if fullLength != len(extended) || offset+len(part) > len(extended) {
+			return Invalid, nil
+		}
+
+		filled += copy(extended[offset:], part)
+	}
+
+	if extended == nil || filled != len(extended) {
+		return Invalid, nil
+	}
+
+	return Valid, extended
+}
+
+type jpegSegment struct {
+	marker byte
+
+	// Offset of the payload following the length field
+	dataStart int
+	end       int
+}
+
+// jpegNextSegment reads the header of the marker segment at offset i, skipping fill
+// bytes and markers without a payload.
+func jpegNextSegment(p []byte, i int) (Result, jpegSegment) {
+	for {
+		if len(p) < i+2 {
+			return NeedMoreData, jpegSegment{}
+		}
+
+		if p[i] != '\xff' {
+			return Invalid, jpegSegment{}
+		}
+
+		marker := p[i+1]
+
+		switch {
+		case marker == '\xff':
+			// Fill byte
+			i++
+			continue
+		case marker == '\x01', marker >= '\xd0' && marker <= '\xd8':
+			// TEM, RSTn and SOI
+			i += 2
+			continue
+		case marker == '\xd9':
+			// EOI
+			return Invalid, jpegSegment{}
+		}
+
+		if len(p) < i+4 {
+			return NeedMoreData, jpegSegment{}
+		}
+
+		length := int(binary.BigEndian.Uint16(p[i+2:]))
+		if length < 2 {
+			return Invalid, jpegSegment{}
+		}
+
+		return Valid, jpegSegment{marker: marker, dataStart: i + 4, end: i + 2 + length}
+	}
+}
+
+// jpegFindSegment returns the first segment with the given marker whose payload
+// starts with identifier. The rest of the payload might not be available yet.
+// Application segments are stored in front of the frame header, so the search ends
+// at the first SOF or SOS segment.
+func jpegFindSegment(p []byte, marker byte, identifier []byte) (Result, jpegSegment) {
+	for i := 2; ; {
+		result, segment := jpegNextSegment(p, i)
+		if result != Valid {
+			return result, jpegSegment{}
+		}
+
+		if jpegIsFrameHeader(segment.marker) || segment.marker == '\xda' {
+			return Invalid, jpegSegment{}
+		}
+
+		if segment.marker == marker && segment.end >= segment.dataStart+len(identifier) {
+			if len(p) < segment.dataStart+len(identifier) {
+				return NeedMoreData, jpegSegment{}
+			}
+
+			if bytes.Equal(p[segment.dataStart:segment.dataStart+len(identifier)], identifier) {
+				return Valid, segment
+			}
+		}
+
+		i = segment.end
+	}
+}
+
+// jpegFindSegments returns all segments with the given marker whose payload starts
+// with identifier, in front of the first SOF or SOS segment. All of them have to be
+// complete. Invalid is returned if the segments can not be read up to there.
+func jpegFindSegments(p []byte, marker byte, identifier []byte) (Result, []jpegSegment) {
+	var segments []jpegSegment
+
+	for i := 2; ; {
+		result, segment := jpegNextSegment(p, i)
+		if result != Valid {
+			return result, nil
+		}
+
+		if jpegIsFrameHeader(segment.marker) || segment.marker == '\xda' {
+			return Valid, segments
+		}
+
+		if segment.marker == marker && segment.end >= segment.dataStart+len(identifier) {
+			if len(p) < segment.dataStart+len(identifier) {
+				return NeedMoreData, nil
+			}
+
+			if bytes.Equal(p[segment.dataStart:segment.dataStart+len(identifier)], identifier) {
+				if len(p) < segment.end {
+					return NeedMoreData, nil
+				}
+
+				segments = append(segments, segment)
+			}
+		}
+
+		i = segment.end
+	}
+}
+
+// jpegIsFrameHeader returns true for the SOFn markers, excluding DHT, JPG and DAC
+// which share the same range.
+func jpegIsFrameHeader(marker byte) bool {
+	if marker < '\xc0' || marker > '\xcf' {
+		return false
+	}
+
+	return marker != '\xc4' && marker != '\xc8' && marker != '\xcc'
+}
+
 func init() {
 	register(&JPEGParser{})
 }
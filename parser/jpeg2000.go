@@ -0,0 +1,365 @@
+package parser
+
+import (
+	"bytes"
+	"encoding/binary"
+)
+
+// Information about the jpeg 2000 structure can be found here:
+// https://www.itu.int/rec/T-REC-T.800
+// https://en.wikipedia.org/wiki/JPEG_2000#File_format_and_codestream
+
+type JP2Parser struct{}
+
+type JPXParser struct{}
+
+type J2KParser struct{}
+
+type JPEG2000Header struct {
+	// Size of the reference grid minus the image offset
+	Size ImageSize
+
+	Components uint16
+
+	// Bits per component, 0 if the components differ in their bit depth
+	BitDepth uint8
+
+	// Component values are signed
+	Signed bool
+}
+
+var jp2Signature = []byte{'\x00', '\x00', '\x00', '\x0c', 'j', 'P', ' ', ' ', '\x0d', '\x0a', '\x87', '\x0a'}
+
+func (J JP2Parser) Type() ImageType {
+	return JP2
+}
+
+func (J JP2Parser) DetectType(p []byte) (r Result) {
+	return jp2DetectType(p, JP2)
+}
+
+func (J JP2Parser) GetSize(p []byte) (r Result, t ImageSize) {
+	result, header := J.GetHeader(p)
+	return result, header.Size
+}
+
+// GetHeader reads the image header box of the jp2 header box.
+func (J JP2Parser) GetHeader(p []byte) (r Result, h JPEG2000Header) {
+	if result := J.DetectType(p); result != Valid {
+		return result, JPEG2000Header{}
+	}
+
+	return jp2GetHeader(p)
+}
+
+func (J JP2Parser) GetPixelFormat(p []byte) (r Result, f PixelFormat) {
+	if result := J.DetectType(p); result != Valid {
+		return result, PixelFormat{}
+	}
+
+	return jp2GetPixelFormat(p)
+}
+
+func (J JPXParser) Type() ImageType {
+	return JPX
+}
+
+func (J JPXParser) DetectType(p []byte) (r Result) {
+	return jp2DetectType(p, JPX)
+}
+
+func (J JPXParser) GetSize(p []byte) (r Result, t ImageSize) {
+	result, header := J.GetHeader(p)
+	return result, header.Size
+}
+
+// GetHeader reads the image header box of the jp2 header box.
+func (J JPXParser) GetHeader(p []byte) (r Result, h JPEG2000Header) {
+	if result := J.DetectType(p); result != Valid {
+		return result, JPEG2000Header{}
+	}
+
+	return jp2GetHeader(p)
+}
+
+func (J JPXParser) GetPixelFormat(p []byte) (r Result, f PixelFormat) {
+	if result := J.DetectType(p); result != Valid {
+		return result, PixelFormat{}
+	}
+
+	return jp2GetPixelFormat(p)
+}
+
+func (J J2KParser) Type() ImageType {
+	return J2K
+}
+
+func (J J2KParser) DetectType(p []byte) (r Result) {
+	// SOC marker followed by the SIZ marker
+	j2kStart := []byte{'\xff', '\x4f', '\xff', '\x51'}
+
+	if len(p) < len(j2kStart) {
+		if bytes.Equal(p, j2kStart[:len(p)]) {
+			return NeedMoreData
+		}
+		return Invalid
+	}
+
+	if bytes.Equal(p[:len(j2kStart)], j2kStart) {
+		return Valid
+	}
+
+	return Invalid
+}
+
+func (J J2KParser) GetSize(p []byte) (r Result, t ImageSize) {
+	result, header := J.GetHeader(p)
+	return result, header.Size
+}
+
+// GetHeader reads the SIZ marker segment of the codestream.
+func (J J2KParser) GetHeader(p []byte) (r Result, h JPEG2000Header) {
+	if result := J.DetectType(p); result != Valid {
+		return result, JPEG2000Header{}
+	}
+
+	return j2kParseSIZ(p[2:])
+}
+
+// GetPixelFormat derives the pixel format from the SIZ marker segment. The codestream
+// does not describe its color space, so one component is reported as gray and three
+// components as RGB.
+func (J J2KParser) GetPixelFormat(p []byte) (r Result, f PixelFormat) {
+	result, header := J.GetHeader(p)
+	if result != Valid {
+		return result, PixelFormat{}
+	}
+
+	pixelFormat := PixelFormat{BitDepth: header.BitDepth, Channels: header.Components}
+
+	switch header.Components {
+	case 1:
+		pixelFormat.ColorModel = ColorModelGray
+	case 3:
+		pixelFormat.ColorModel = ColorModelRGB
+	}
+
+	return Valid, pixelFormat
+}
+
+func jp2DetectType(p []byte, expectedImageType ImageType) Result {
+	if len(p) < len(jp2Signature) {
+		if bytes.Equal(p, jp2Signature[:len(p)]) {
+			return NeedMoreData
+		}
+		return Invalid
+	}
+
+	if !bytes.Equal(p[:len(jp2Signature)], jp2Signature) {
+		return Invalid
+	}
+
+	// File type box follows the signature box
+	result, majorBrand, compatibleBrands := isobmffBrandsAt(p, len(jp2Signature))
+	if result != Valid {
+		return result
+	}
+
+	imageType := UnknownType
+	for _, brand := range append([]string{majorBrand}, compatibleBrands...) {
+		if brand == "jpx " {
+			imageType = JPX
+			break
+		}
+
+		if brand == "jp2 " && imageType == UnknownType {
+			imageType = JP2
+		}
+	}
+
+	if imageType != expectedImageType {
+		return Invalid
+	}
+
+	return Valid
+}
+
+func jp2GetHeader(p []byte) (Result, JPEG2000Header) {
+	result, jp2h := isobmffFindBox(p, len(jp2Signature), -1, "jp2h")
+	if result != Valid {
+		return result, JPEG2000Header{}
+	}
+
+	if jp2h.end == -1 {
+		return Invalid, JPEG2000Header{}
+	}
+
+	result, ihdr := isobmffFindBox(p, jp2h.dataStart, jp2h.end, "ihdr")
+	if result != Valid {
+		return result, JPEG2000Header{}
+	}
+
+	// Height, width, components, bits per component, compression type,
+	// unknown colorspace and intellectual property
+	i := ihdr.dataStart
+	if ihdr.end < i+14 {
+		return Invalid, JPEG2000Header{}
+	}
+
+	if len(p) < i+14 {
+		return NeedMoreData, JPEG2000Header{}
+	}
+
+	header := JPEG2000Header{
+		Components: binary.BigEndian.Uint16(p[i+8:]),
+	}
+
+	header.Size.Height = binary.BigEndian.Uint32(p[i:])
+	header.Size.Width = binary.BigEndian.Uint32(p[i+4:])
+
+	// 255 signals that the bit depth differs between components
+	if bitsPerComponent := p[i+10]; bitsPerComponent != 255 {
+		header.BitDepth = bitsPerComponent&0x7f + 1
+		header.Signed = bitsPerComponent&0x80 != 0
+	}
+
+	return Valid, header
+}
+
+// jp2GetPixelFormat combines the image header with the color specification, palette
+// and channel definition boxes of the jp2 header box.
+func jp2GetPixelFormat(p []byte) (Result, PixelFormat) {
+	result, header := jp2GetHeader(p)
+	if result != Valid {
+		return result, PixelFormat{}
+	}
+
+	_, jp2h := isobmffFindBox(p, len(jp2Signature), -1, "jp2h")
+	if len(p) < jp2h.end {
+		return NeedMoreData, PixelFormat{}
+	}
+
+	result, boxes := isobmffChildren(p, jp2h.dataStart, jp2h.end)
+	if result != Valid {
+		return result, PixelFormat{}
+	}
+
+	pixelFormat := PixelFormat{BitDepth: header.BitDepth, Channels: header.Components}
+	hasColorSpecification, palette := false, false
+
+	for _, box := range boxes {
+		i := box.dataStart
+
+		switch box.boxType {
+		case "colr":
+			// Only the first color specification has to be understood by readers
+			if hasColorSpecification {
+				continue
+			}
+			hasColorSpecification = true
+
+			// Method, precedence and approximation, followed by the enumerated
+			// colorspace or an ICC profile
+			if box.end < i+7 {
+				continue
+			}
+
+			if p[i] == 1 {
+				switch binary.BigEndian.Uint32(p[i+3:]) {
+				case 0, 17:
+					pixelFormat.ColorModel = ColorModelGray
+				case 3, 4, 5, 18, 22, 23, 24:
+					pixelFormat.ColorModel = ColorModelYCbCr
+				case 12, 13:
+					pixelFormat.ColorModel = ColorModelCMYK
+				case 16, 20, 21:
+					pixelFormat.ColorModel = ColorModelRGB
+				}
+			} else if header.Components < 3 {
+				pixelFormat.ColorModel = ColorModelGray
+			} else {
+				pixelFormat.ColorModel = ColorModelRGB
+			}
+		case "pclr":
+			palette = true
+		case "cdef":
+			// Number of channels, followed by the index, type and association of each
+			if box.end < i+2 {
+				continue
+			}
+
+			count := int(binary.BigEndian.Uint16(p[i:]))
+			if box.end < i+2+6*count {
+				continue
+			}
+
+			// Type 1 and 2 are opacity and premultiplied opacity
+			for j := 0; j < count; j++ {
+				channelType := binary.BigEndian.Uint16(p[i+2+6*j+2:])
+				if channelType == 1 || channelType == 2 {
+					pixelFormat.Alpha = true
+				}
+			}
+		}
+	}
+
+	if palette {
+		pixelFormat.ColorModel = ColorModelPalette
+	}
+
+	return Valid, pixelFormat
+}
+
+// j2kParseSIZ reads the SIZ marker segment at the start of p.
+func j2kParseSIZ(p []byte) (Result, JPEG2000Header) {
+	// Marker, Lsiz, Rsiz, Xsiz, Ysiz, XOsiz, YOsiz, XTsiz, YTsiz, XTOsiz, YTOsiz
+	// and Csiz, followed by Ssiz, XRsiz and YRsiz for each component
+	if len(p) < 40 {
+		return NeedMoreData, JPEG2000Header{}
+	}
+
+	if p[0] != '\xff' || p[1] != '\x51' {
+		return Invalid, JPEG2000Header{}
+	}
+
+	width := binary.BigEndian.Uint32(p[6:])
+	height := binary.BigEndian.Uint32(p[10:])
+	offsetX := binary.BigEndian.Uint32(p[14:])
+	offsetY := binary.BigEndian.Uint32(p[18:])
+
+	if offsetX > width || offsetY > height {
+		return Invalid, JPEG2000Header{}
+	}
+
+	header := JPEG2000Header{
+		Size:       ImageSize{Width: width - offsetX, Height: height - offsetY},
+		Components: binary.BigEndian.Uint16(p[38:]),
+	}
+
+	if header.Components == 0 {
+		return Invalid, JPEG2000Header{}
+	}
+
+	if len(p) < 40+3*int(header.Components) {
+		return NeedMoreData, JPEG2000Header{}
+	}
+
+	for c := 0; c < int(header.Components); c++ {
+		precision := p[40+3*c]
+
+		if c == 0 {
+			header.BitDepth = precision&0x7f + 1
+			header.Signed = precision&0x80 != 0
+		} else if precision&0x7f+1 != header.BitDepth {
+			header.BitDepth = 0
+		}
+	}
+
+	return Valid, header
+}
+
+func init() {
+	register(&JP2Parser{})
+	register(&JPXParser{})
+	register(&J2KParser{})
+}
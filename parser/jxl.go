@@ -0,0 +1,381 @@
+package parser
+
+import (
+	"bytes"
+)
+
+// Information about the jpeg xl structure can be found here:
+// https://github.com/libjxl/libjxl/blob/main/doc/format_overview.md
+// https://www.iso.org/standard/77977.html
+
+type JXLParser struct{}
+
+var jxlCodestreamSignature = []byte{'\xff', '\x0a'}
+var jxlContainerSignature = []byte{'\x00', '\x00', '\x00', '\x0c', 'J', 'X', 'L', ' ', '\x0d', '\x0a', '\x87', '\x0a'}
+
+func (J JXLParser) Type() ImageType {
+	return JXL
+}
+
+func (J JXLParser) DetectType(p []byte) (r Result) {
+	if len(p) < len(jxlCodestreamSignature) {
+		return NeedMoreData
+	}
+
+	if bytes.Equal(p[:len(jxlCodestreamSignature)], jxlCodestreamSignature) {
+		return Valid
+	}
+
+	if len(p) < len(jxlContainerSignature) {
+		if bytes.Equal(p, jxlContainerSignature[:len(p)]) {
+			return NeedMoreData
+		}
+		return Invalid
+	}
+
+	if bytes.Equal(p[:len(jxlContainerSignature)], jxlContainerSignature) {
+		return Valid
+	}
+
+	return Invalid
+}
+
+func (J JXLParser) GetSize(p []byte) (r Result, t ImageSize) {
+	if result := J.DetectType(p); result != Valid {
+		return result, ImageSize{}
+	}
+
+	result, codestream := jxlCodestream(p)
+	if result != Valid {
+		return result, ImageSize{}
+	}
+
+	return jxlParseSizeHeader(&jxlBitReader{p: codestream})
+}
+
+// GetPixelFormat reads the ImageMetadata following the SizeHeader, up to the color
+// encoding. Extra channels of type alpha set the alpha flag, an extra channel of
+// type black turns RGB into CMYK.
+func (J JXLParser) GetPixelFormat(p []byte) (r Result, f PixelFormat) {
+	if result := J.DetectType(p); result != Valid {
+		return result, PixelFormat{}
+	}
+
+	result, codestream := jxlCodestream(p)
+	if result != Valid {
+		return result, PixelFormat{}
+	}
+
+	b := &jxlBitReader{p: codestream}
+
+	if result, _ := jxlParseSizeHeader(b); result != Valid {
+		return result, PixelFormat{}
+	}
+
+	return jxlParseImageMetadata(b)
+}
+
+// jxlCodestream returns the codestream following the signature, which is either
+// stored directly inside of the file or inside of a jxlc box or jxlp boxes.
+func jxlCodestream(p []byte) (Result, []byte) {
+	if bytes.Equal(p[:len(jxlCodestreamSignature)], jxlCodestreamSignature) {
+		return Valid, p[len(jxlCodestreamSignature):]
+	}
+
+	result, box := isobmffFindBox(p, len(jxlContainerSignature), -1, "jxlc", "jxlp")
+	if result != Valid {
+		return result, nil
+	}
+
+	start := box.dataStart
+
+	// Partial codestream boxes start with their index
+	if box.boxType == "jxlp" {
+		start += 4
+	}
+
+	end := box.end
+	if end == -1 || end > len(p) {
+		end = len(p)
+	}
+
+	if end < start+len(jxlCodestreamSignature) {
+		return NeedMoreData, nil
+	}
+
+	if !bytes.Equal(p[start:start+len(jxlCodestreamSignature)], jxlCodestreamSignature) {
+		return Invalid, nil
+	}
+
+	return Valid, p[start+len(jxlCodestreamSignature) : end]
+}
+
+// jxlBitReader reads the least significant bits of each byte first.
+type jxlBitReader struct {
+	p   []byte
+	pos int
+}
+
+func (b *jxlBitReader) read(n int) (uint32, bool) {
+	if len(b.p)*8 < b.pos+n {
+		return 0, false
+	}
+
+	var value uint32
+	for i := 0; i < n; i++ {
+		bit := (b.p[b.pos/8] >> uint(b.pos%8)) & 1
+		value |= uint32(bit) << uint(i)
+		b.pos++
+	}
+
+	return value, true
+}
+
+// readU32 reads a value whose distribution is selected by two leading bits, each
+// selecting the number of bits to read and an offset to add.
+func (b *jxlBitReader) readU32(bits [4]int, offsets [4]uint32) (uint32, bool) {
+	selector, ok := b.read(2)
+	if !ok {
+		return 0, false
+	}
+
+	value, ok := b.read(bits[selector])
+	if !ok {
+		return 0, false
+	}
+
+	return value + offsets[selector], true
+}
+
+// Width to height ratios which can be used instead of storing the width
+var jxlRatios = [8][2]uint64{{0, 0}, {1, 1}, {12, 10}, {4, 3}, {3, 2}, {16, 9}, {5, 4}, {2, 1}}
+
+// jxlReadDimension reads a height or width of the SizeHeader.
+func jxlReadDimension(b *jxlBitReader, small bool) (uint32, bool) {
+	if small {
+		value, ok := b.read(5)
+		return (value + 1) * 8, ok
+	}
+
+	return b.readU32([4]int{9, 13, 18, 30}, [4]uint32{1, 1, 1, 1})
+}
+
+// jxlParseSizeHeader reads the SizeHeader, which directly follows the codestream
+// signature.
+func jxlParseSizeHeader(b *jxlBitReader) (Result, ImageSize) {
+	small, ok := b.read(1)
+	if !ok {
+		return NeedMoreData, ImageSize{}
+	}
+
+	height, ok := jxlReadDimension(b, small == 1)
+	if !ok {
+		return NeedMoreData, ImageSize{}
+	}
+
+	ratio, ok := b.read(3)
+	if !ok {
+		return NeedMoreData, ImageSize{}
+	}
+
+	var width uint32
+	if ratio == 0 {
+		width, ok = jxlReadDimension(b, small == 1)
+		if !ok {
+			return NeedMoreData, ImageSize{}
+		}
+	} else {
+		width = uint32(uint64(height) * jxlRatios[ratio][0] / jxlRatios[ratio][1])
+	}
+
+	return Valid, ImageSize{Width: width, Height: height}
+}
+
+// Types of extra channels whose fields have to be read or which change the pixel
+// format
+const (
+	jxlExtraChannelAlpha     = 0
+	jxlExtraChannelSpotColor = 2
+	jxlExtraChannelBlack     = 4
+	jxlExtraChannelCFA       = 5
+)
+
+// jxlParseImageMetadata reads the fields of the ImageMetadata bundle that are needed
+// to derive the pixel format. Fields of nested bundles are skipped as they are read.
+func jxlParseImageMetadata(b *jxlBitReader) (Result, PixelFormat) {
+	// Reading past the available data sets ok to false, all reads after that fail
+	ok := true
+
+	readBits := func(n int) uint32 {
+		if !ok {
+			return 0
+		}
+
+		var value uint32
+		value, ok = b.read(n)
+		return value
+	}
+
+	readBool := func() bool {
+		return readBits(1) == 1
+	}
+
+	readU32 := func(bits [4]int, offsets [4]uint32) uint32 {
+		if !ok {
+			return 0
+		}
+
+		var value uint32
+		value, ok = b.readU32(bits, offsets)
+		return value
+	}
+
+	readEnum := func() uint32 {
+		return readU32([4]int{0, 0, 4, 6}, [4]uint32{0, 1, 2, 18})
+	}
+
+	// BitDepth bundle, returns the bits per sample
+	readBitDepth := func() uint32 {
+		if readBool() {
+			// Floating point samples, followed by the exponent bits
+			bits := readU32([4]int{0, 0, 0, 6}, [4]uint32{32, 16, 24, 1})
+			readBits(4)
+			return bits
+		}
+
+		return readU32([4]int{0, 0, 0, 6}, [4]uint32{8, 10, 12, 1})
+	}
+
+	// All default means 8 bit sRGB without extra channels
+	if readBool() {
+		if !ok {
+			return NeedMoreData, PixelFormat{}
+		}
+
+		return Valid, PixelFormat{BitDepth: 8, Channels: 3, ColorModel: ColorModelRGB}
+	}
+
+	// Extra fields
+	if readBool() {
+		// Orientation
+		readBits(3)
+
+		// Intrinsic size
+		if readBool() {
+			if result, _ := jxlParseSizeHeader(b); result != Valid {
+				return NeedMoreData, PixelFormat{}
+			}
+		}
+
+		// Preview header
+		if readBool() {
+			div8 := readBool()
+
+			readPreviewDimension := func() {
+				if div8 {
+					readU32([4]int{0, 0, 5, 9}, [4]uint32{16, 32, 1, 33})
+				} else {
+					readU32([4]int{6, 8, 10, 12}, [4]uint32{1, 65, 321, 1345})
+				}
+			}
+
+			readPreviewDimension()
+			if readBits(3) == 0 {
+				readPreviewDimension()
+			}
+		}
+
+		// Animation header with the tick rate, loop count and timecode flag
+		if readBool() {
+			readU32([4]int{0, 0, 10, 30}, [4]uint32{100, 1000, 1, 1})
+			readU32([4]int{0, 0, 8, 10}, [4]uint32{1, 1001, 1, 1})
+			readU32([4]int{0, 3, 16, 32}, [4]uint32{0, 0, 0, 0})
+			readBool()
+		}
+	}
+
+	pixelFormat := PixelFormat{BitDepth: uint8(readBitDepth())}
+
+	// Modular 16 bit buffers are sufficient
+	readBool()
+
+	extraChannels := readU32([4]int{0, 0, 4, 12}, [4]uint32{0, 1, 2, 1})
+	black := false
+
+	for j := uint32(0); j < extraChannels && ok; j++ {
+		// All default means an 8 bit alpha channel
+		if readBool() {
+			pixelFormat.Alpha = true
+			continue
+		}
+
+		channelType := readEnum()
+		readBitDepth()
+
+		// Dimension shift
+		readU32([4]int{0, 0, 0, 3}, [4]uint32{0, 3, 4, 1})
+
+		// Name
+		nameLength := readU32([4]int{0, 4, 5, 10}, [4]uint32{0, 0, 16, 48})
+		for k := uint32(0); k < nameLength && ok; k++ {
+			readBits(8)
+		}
+
+		switch channelType {
+		case jxlExtraChannelAlpha:
+			pixelFormat.Alpha = true
+
+			// Alpha is premultiplied
+			readBool()
+		case jxlExtraChannelSpotColor:
+			// Red, green, blue and solidity as 16 bit floats
+			for k := 0; k < 4; k++ {
+				readBits(16)
+			}
+		case jxlExtraChannelCFA:
+			// Index of the color filter array channel
+			readU32([4]int{0, 2, 4, 8}, [4]uint32{1, 0, 3, 19})
+		case jxlExtraChannelBlack:
+			black = true
+		}
+	}
+
+	// XYB encoded, followed by the color encoding whose default is sRGB
+	readBool()
+
+	colorModel := ColorModelRGB
+	if !readBool() {
+		// Embedded ICC profile, followed by the color space
+		readBool()
+
+		switch readEnum() {
+		case 1:
+			colorModel = ColorModelGray
+		case 3:
+			colorModel = ColorModelUnknown
+		}
+	}
+
+	if !ok {
+		return NeedMoreData, PixelFormat{}
+	}
+
+	if black && colorModel == ColorModelRGB {
+		colorModel = ColorModelCMYK
+	}
+
+	pixelFormat.ColorModel = colorModel
+	pixelFormat.Channels = uint16(extraChannels)
+
+	if colorModel == ColorModelGray {
+		pixelFormat.Channels++
+	} else {
+		pixelFormat.Channels += 3
+	}
+
+	return Valid, pixelFormat
+}
+
+func init() {
+	register(&JXLParser{})
+}
@@ -0,0 +1,129 @@
+package parser
+
+import (
+	"bytes"
+	"encoding/binary"
+)
+
+// https://registry.khronos.org/KTX/specs/1.0/ktxspec.v1.html
+// https://registry.khronos.org/KTX/specs/2.0/ktxspec.v2.html
+
+type KTXParser struct{}
+
+type KTX2Parser struct{}
+
+var ktxIdentifier = []byte{'\xab', 'K', 'T', 'X', ' ', '1', '1', '\xbb', '\r', '\n', '\x1a', '\n'}
+var ktx2Identifier = []byte{'\xab', 'K', 'T', 'X', ' ', '2', '0', '\xbb', '\r', '\n', '\x1a', '\n'}
+
+func (K KTXParser) Type() ImageType {
+	return KTX
+}
+
+func (K KTXParser) DetectType(p []byte) (r Result) {
+	return ktxDetectType(p, ktxIdentifier)
+}
+
+func (K KTXParser) GetSize(p []byte) (r Result, t ImageSize) {
+	result, textureInfo := K.GetTextureInfo(p)
+	if result != Valid {
+		return result, ImageSize{}
+	}
+
+	return Valid, textureInfo.Size
+}
+
+// GetTextureInfo reads the 64 byte KTX header.
+func (K KTXParser) GetTextureInfo(p []byte) (r Result, t TextureInfo) {
+	if result := K.DetectType(p); result != Valid {
+		return result, TextureInfo{}
+	}
+
+	if len(p) < 64 {
+		return NeedMoreData, TextureInfo{}
+	}
+
+	// The endianness field is written in the byte order of the file
+	var byteOrder binary.ByteOrder
+	switch binary.LittleEndian.Uint32(p[12:]) {
+	case 0x04030201:
+		byteOrder = binary.LittleEndian
+	case 0x01020304:
+		byteOrder = binary.BigEndian
+	default:
+		return Invalid, TextureInfo{}
+	}
+
+	textureInfo := TextureInfo{
+		GLInternalFormat: byteOrder.Uint32(p[28:]),
+		Depth:            atLeastOne(byteOrder.Uint32(p[44:])),
+		ArrayLayers:      atLeastOne(byteOrder.Uint32(p[48:])),
+		Faces:            atLeastOne(byteOrder.Uint32(p[52:])),
+		MipLevels:        atLeastOne(byteOrder.Uint32(p[56:])),
+	}
+
+	textureInfo.Size.Width = byteOrder.Uint32(p[36:])
+	textureInfo.Size.Height = atLeastOne(byteOrder.Uint32(p[40:]))
+
+	return Valid, textureInfo
+}
+
+func (K KTX2Parser) Type() ImageType {
+	return KTX2
+}
+
+func (K KTX2Parser) DetectType(p []byte) (r Result) {
+	return ktxDetectType(p, ktx2Identifier)
+}
+
+func (K KTX2Parser) GetSize(p []byte) (r Result, t ImageSize) {
+	result, textureInfo := K.GetTextureInfo(p)
+	if result != Valid {
+		return result, ImageSize{}
+	}
+
+	return Valid, textureInfo.Size
+}
+
+// GetTextureInfo reads the KTX2 header, which is always little endian.
+func (K KTX2Parser) GetTextureInfo(p []byte) (r Result, t TextureInfo) {
+	if result := K.DetectType(p); result != Valid {
+		return result, TextureInfo{}
+	}
+
+	if len(p) < 12+36 {
+		return NeedMoreData, TextureInfo{}
+	}
+
+	textureInfo := TextureInfo{
+		VkFormat:    binary.LittleEndian.Uint32(p[12:]),
+		Depth:       atLeastOne(binary.LittleEndian.Uint32(p[28:])),
+		ArrayLayers: atLeastOne(binary.LittleEndian.Uint32(p[32:])),
+		Faces:       atLeastOne(binary.LittleEndian.Uint32(p[36:])),
+		MipLevels:   atLeastOne(binary.LittleEndian.Uint32(p[40:])),
+	}
+
+	textureInfo.Size.Width = binary.LittleEndian.Uint32(p[20:])
+	textureInfo.Size.Height = atLeastOne(binary.LittleEndian.Uint32(p[24:]))
+
+	return Valid, textureInfo
+}
+
+func ktxDetectType(p []byte, identifier []byte) Result {
+	if len(p) < len(identifier) {
+		if bytes.Equal(p, identifier[:len(p)]) {
+			return NeedMoreData
+		}
+		return Invalid
+	}
+
+	if bytes.Equal(p[:len(identifier)], identifier) {
+		return Valid
+	}
+
+	return Invalid
+}
+
+func init() {
+	register(&KTXParser{})
+	register(&KTX2Parser{})
+}
@@ -0,0 +1,433 @@
+package parser
+
+import (
+	"bytes"
+	"math/bits"
+	"strconv"
+	"strings"
+)
+
+// Information about the netpbm formats can be found here:
+// https://netpbm.sourceforge.net/doc/pbm.html
+// https://netpbm.sourceforge.net/doc/pgm.html
+// https://netpbm.sourceforge.net/doc/ppm.html
+// https://netpbm.sourceforge.net/doc/pam.html
+// https://netpbm.sourceforge.net/doc/pfm.html
+
+type PBMParser struct{}
+
+type PGMParser struct{}
+
+type PPMParser struct{}
+
+type PAMParser struct{}
+
+type PFMParser struct{}
+
+type NetpbmHeader struct {
+	// Magic number, e.g. P6
+	Magic string
+
+	Size ImageSize
+
+	// Maximum sample value, always 1 for PBM and 0 for PFM
+	MaxVal uint32
+
+	// Number of channels per pixel
+	Depth uint32
+
+	// Tuple type of PAM files, e.g. RGB_ALPHA
+	TupleType string
+
+	// Scale factor of PFM files, negative values mean little endian samples
+	Scale float64
+
+	// Samples are stored as ASCII decimal numbers (P1, P2, P3)
+	Plain bool
+}
+
+func (P PBMParser) Type() ImageType {
+	return PBM
+}
+
+func (P PBMParser) DetectType(p []byte) (r Result) {
+	return netpbmDetectType(p, PBM)
+}
+
+func (P PBMParser) GetSize(p []byte) (r Result, t ImageSize) {
+	result, header := netpbmGetHeader(p, PBM)
+	return result, header.Size
+}
+
+func (P PBMParser) GetHeader(p []byte) (r Result, h NetpbmHeader) {
+	return netpbmGetHeader(p, PBM)
+}
+
+func (P PBMParser) GetPixelFormat(p []byte) (r Result, f PixelFormat) {
+	return netpbmGetPixelFormat(p, PBM)
+}
+
+func (P PGMParser) Type() ImageType {
+	return PGM
+}
+
+func (P PGMParser) DetectType(p []byte) (r Result) {
+	return netpbmDetectType(p, PGM)
+}
+
+func (P PGMParser) GetSize(p []byte) (r Result, t ImageSize) {
+	result, header := netpbmGetHeader(p, PGM)
+	return result, header.Size
+}
+
+func (P PGMParser) GetHeader(p []byte) (r Result, h NetpbmHeader) {
+	return netpbmGetHeader(p, PGM)
+}
+
+func (P PGMParser) GetPixelFormat(p []byte) (r Result, f PixelFormat) {
+	return netpbmGetPixelFormat(p, PGM)
+}
+
+func (P PPMParser) Type() ImageType {
+	return PPM
+}
+
+func (P PPMParser) DetectType(p []byte) (r Result) {
+	return netpbmDetectType(p, PPM)
+}
+
+func (P PPMParser) GetSize(p []byte) (r Result, t ImageSize) {
+	result, header := netpbmGetHeader(p, PPM)
+	return result, header.Size
+}
+
+func (P PPMParser) GetHeader(p []byte) (r Result, h NetpbmHeader) {
+	return netpbmGetHeader(p, PPM)
+}
+
+func (P PPMParser) GetPixelFormat(p []byte) (r Result, f PixelFormat) {
+	return netpbmGetPixelFormat(p, PPM)
+}
+
+func (P PAMParser) Type() ImageType {
+	return PAM
+}
+
+func (P PAMParser) DetectType(p []byte) (r Result) {
+	return netpbmDetectType(p, PAM)
+}
+
+func (P PAMParser) GetSize(p []byte) (r Result, t ImageSize) {
+	result, header := netpbmGetHeader(p, PAM)
+	return result, header.Size
+}
+
+func (P PAMParser) GetHeader(p []byte) (r Result, h NetpbmHeader) {
+	return netpbmGetHeader(p, PAM)
+}
+
+func (P PAMParser) GetPixelFormat(p []byte) (r Result, f PixelFormat) {
+	return netpbmGetPixelFormat(p, PAM)
+}
+
+func (P PFMParser) Type() ImageType {
+	return PFM
+}
+
+func (P PFMParser) DetectType(p []byte) (r Result) {
+	return netpbmDetectType(p, PFM)
+}
+
+func (P PFMParser) GetSize(p []byte) (r Result, t ImageSize) {
+	result, header := netpbmGetHeader(p, PFM)
+	return result, header.Size
+}
+
+func (P PFMParser) GetHeader(p []byte) (r Result, h NetpbmHeader) {
+	return netpbmGetHeader(p, PFM)
+}
+
+func (P PFMParser) GetPixelFormat(p []byte) (r Result, f PixelFormat) {
+	return netpbmGetPixelFormat(p, PFM)
+}
+
+// netpbmImageType returns the image type of a magic number.
+func netpbmImageType(magic byte) ImageType {
+	switch magic {
+	case '1', '4':
+		return PBM
+	case '2', '5':
+		return PGM
+	case '3', '6':
+		return PPM
+	case '7':
+		return PAM
+	case 'F', 'f':
+		return PFM
+	default:
+		return UnknownType
+	}
+}
+
+func netpbmDetectType(p []byte, expectedImageType ImageType) Result {
+	// Magic number followed by whitespace
+	if len(p) < 3 {
+		return NeedMoreData
+	}
+
+	if p[0] != 'P' || !netpbmIsWhitespace(p[2]) {
+		return Invalid
+	}
+
+	if netpbmImageType(p[1]) != expectedImageType {
+		return Invalid
+	}
+
+	return Valid
+}
+
+func netpbmGetHeader(p []byte, expectedImageType ImageType) (Result, NetpbmHeader) {
+	if result := netpbmDetectType(p, expectedImageType); result != Valid {
+		return result, NetpbmHeader{}
+	}
+
+	header := NetpbmHeader{Magic: string(p[0:2])}
+
+	switch p[1] {
+	case '1', '2', '3':
+		header.Plain = true
+	}
+
+	if p[1] == '7' {
+		return netpbmParsePAMHeader(p, header)
+	}
+
+	// Width and height follow the magic number for all formats except PAM
+	result, width, i := netpbmNextNumber(p, 2)
+	if result != Valid {
+		return result, NetpbmHeader{}
+	}
+
+	result, height, i := netpbmNextNumber(p, i)
+	if result != Valid {
+		return result, NetpbmHeader{}
+	}
+
+	header.Size = ImageSize{Width: width, Height: height}
+
+	switch p[1] {
+	case '1', '4':
+		header.MaxVal = 1
+		header.Depth = 1
+		return Valid, header
+
+	case 'F', 'f':
+		header.Depth = 1
+		if p[1] == 'F' {
+			header.Depth = 3
+		}
+
+		result, token, _ := netpbmNextToken(p, i)
+		if result != Valid {
+			return result, NetpbmHeader{}
+		}
+
+		scale, err := strconv.ParseFloat(token, 64)
+		if err != nil || scale == 0 {
+			return Invalid, NetpbmHeader{}
+		}
+
+		header.Scale = scale
+		return Valid, header
+	}
+
+	result, maxVal, _ := netpbmNextNumber(p, i)
+	if result != Valid {
+		return result, NetpbmHeader{}
+	}
+
+	if maxVal == 0 || maxVal > 65535 {
+		return Invalid, NetpbmHeader{}
+	}
+
+	header.MaxVal = maxVal
+	header.Depth = 1
+	if expectedImageType == PPM {
+		header.Depth = 3
+	}
+
+	return Valid, header
+}
+
+// netpbmParsePAMHeader reads the header lines of a PAM file until ENDHDR.
+func netpbmParsePAMHeader(p []byte, header NetpbmHeader) (Result, NetpbmHeader) {
+	var tupleTypes []string
+	hasWidth, hasHeight, hasDepth, hasMaxVal := false, false, false, false
+
+	// Skip the line holding the magic number
+	i := 3
+
+	for {
+		end := bytes.IndexByte(p[i:], '\n')
+		if end == -1 {
+			return NeedMoreData, NetpbmHeader{}
+		}
+
+		line := strings.TrimSpace(string(p[i : i+end]))
+		i += end + 1
+
+		if line == "" || line[0] == '#' {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		keyword := fields[0]
+
+		if keyword == "ENDHDR" {
+			break
+		}
+
+		if keyword == "TUPLTYPE" {
+			tupleTypes = append(tupleTypes, strings.TrimSpace(line[len(keyword):]))
+			continue
+		}
+
+		if len(fields) != 2 {
+			return Invalid, NetpbmHeader{}
+		}
+
+		value, err := strconv.ParseUint(fields[1], 10, 32)
+		if err != nil {
+			return Invalid, NetpbmHeader{}
+		}
+
+		switch keyword {
+		case "WIDTH":
+			header.Size.Width = uint32(value)
+			hasWidth = true
+		case "HEIGHT":
+			header.Size.Height = uint32(value)
+			hasHeight = true
+		case "DEPTH":
+			header.Depth = uint32(value)
+			hasDepth = true
+		case "MAXVAL":
+			header.MaxVal = uint32(value)
+			hasMaxVal = true
+		default:
+			return Invalid, NetpbmHeader{}
+		}
+	}
+
+	if !hasWidth || !hasHeight || !hasDepth || !hasMaxVal {
+		return Invalid, NetpbmHeader{}
+	}
+
+	if header.MaxVal == 0 || header.MaxVal > 65535 {
+		return Invalid, NetpbmHeader{}
+	}
+
+	// Multiple tuple types are concatenated with a space
+	header.TupleType = strings.Join(tupleTypes, " ")
+
+	return Valid, header
+}
+
+// netpbmGetPixelFormat derives the pixel format from the header. The color model of
+// PAM files is given by their tuple type, the other formats have one or three
+// channels.
+func netpbmGetPixelFormat(p []byte, expectedImageType ImageType) (Result, PixelFormat) {
+	result, header := netpbmGetHeader(p, expectedImageType)
+	if result != Valid {
+		return result, PixelFormat{}
+	}
+
+	// PFM samples are 32 bit floats
+	pixelFormat := PixelFormat{BitDepth: 32, Channels: uint16(header.Depth)}
+	if header.MaxVal > 0 {
+		pixelFormat.BitDepth = uint8(bits.Len32(header.MaxVal))
+	}
+
+	tupleType := header.TupleType
+	if strings.HasSuffix(tupleType, "_ALPHA") {
+		pixelFormat.Alpha = true
+		tupleType = strings.TrimSuffix(tupleType, "_ALPHA")
+	}
+
+	switch {
+	case tupleType == "BLACKANDWHITE", tupleType == "GRAYSCALE":
+		pixelFormat.ColorModel = ColorModelGray
+	case tupleType == "RGB":
+		pixelFormat.ColorModel = ColorModelRGB
+	case tupleType == "" && header.Depth == 1:
+		pixelFormat.ColorModel = ColorModelGray
+	case tupleType == "" && header.Depth == 3:
+		pixelFormat.ColorModel = ColorModelRGB
+	}
+
+	return Valid, pixelFormat
+}
+
+// netpbmNextToken skips whitespace and comments starting at offset i and returns
+// the next token and the offset after it. A token is only complete once it is
+// followed by whitespace.
+func netpbmNextToken(p []byte, i int) (Result, string, int) {
+	for {
+		for i < len(p) && netpbmIsWhitespace(p[i]) {
+			i++
+		}
+
+		if i >= len(p) {
+			return NeedMoreData, "", 0
+		}
+
+		if p[i] != '#' {
+			break
+		}
+
+		// Comments reach until the end of the line
+		end := bytes.IndexAny(p[i:], "\r\n")
+		if end == -1 {
+			return NeedMoreData, "", 0
+		}
+		i += end
+	}
+
+	start := i
+	for i < len(p) && !netpbmIsWhitespace(p[i]) && p[i] != '#' {
+		i++
+	}
+
+	if i >= len(p) {
+		return NeedMoreData, "", 0
+	}
+
+	return Valid, string(p[start:i]), i
+}
+
+// netpbmNextNumber reads the next token as an unsigned decimal number.
+func netpbmNextNumber(p []byte, i int) (Result, uint32, int) {
+	result, token, i := netpbmNextToken(p, i)
+	if result != Valid {
+		return result, 0, 0
+	}
+
+	value, err := strconv.ParseUint(token, 10, 32)
+	if err != nil {
+		return Invalid, 0, 0
+	}
+
+	return Valid, uint32(value), i
+}
+
+func netpbmIsWhitespace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\v' || c == '\f' || c == '\r'
+}
+
+func init() {
+	register(&PBMParser{})
+	register(&PGMParser{})
+	register(&PPMParser{})
+	register(&PAMParser{})
+	register(&PFMParser{})
+}
@@ -0,0 +1,97 @@
+package parser
+
+// Orientation as defined by the Orientation tag of EXIF, which describes how the
+// stored image has to be transformed for display.
+type Orientation uint8
+
+const (
+	OrientationNormal         Orientation = 1
+	OrientationFlipHorizontal Orientation = 2
+	OrientationRotate180      Orientation = 3
+	OrientationFlipVertical   Orientation = 4
+	OrientationTranspose      Orientation = 5
+	OrientationRotate90       Orientation = 6
+	OrientationTransverse     Orientation = 7
+	OrientationRotate270      Orientation = 8
+)
+
+func (o Orientation) String() string {
+	switch o {
+	case OrientationNormal:
+		return "Normal"
+	case OrientationFlipHorizontal:
+		return "FlipHorizontal"
+	case OrientationRotate180:
+		return "Rotate180"
+	case OrientationFlipVertical:
+		return "FlipVertical"
+	case OrientationTranspose:
+		return "Transpose"
+	case OrientationRotate90:
+		return "Rotate90"
+	case OrientationTransverse:
+		return "Transverse"
+	case OrientationRotate270:
+		return "Rotate270"
+	default:
+		return "Unknown"
+	}
+}
+
+// SwapsDimensions returns true if width and height are swapped for display.
+func (o Orientation) SwapsDimensions() bool {
+	return o >= OrientationTranspose && o <= OrientationRotate270
+}
+
+// Apply returns the size of an image with the given stored size after it has been
+// transformed for display.
+func (o Orientation) Apply(size ImageSize) ImageSize {
+	if o.SwapsDimensions() {
+		return ImageSize{Width: size.Height, Height: size.Width}
+	}
+
+	return size
+}
+
+// Implemented by parsers of image formats which can store an orientation. Images
+// without an orientation are reported as OrientationNormal.
+type OrientationParser interface {
+	GetOrientation(p []byte) (Result, Orientation)
+}
+
+// Each orientation expressed as horizontal flip followed by a clockwise rotation in
+// quarter turns, indexed by the orientation value
+var orientationTransforms = [9][2]int{
+	{0, 0},
+	{0, 0}, {1, 0}, {0, 2}, {1, 2},
+	{1, 3}, {0, 1}, {1, 1}, {0, 3},
+}
+
+func orientationFromTransform(flip int, rotation int) Orientation {
+	for o := OrientationNormal; o <= OrientationRotate270; o++ {
+		if orientationTransforms[o] == [2]int{flip, rotation} {
+			return o
+		}
+	}
+
+	return OrientationNormal
+}
+
+// orientationRotate returns the orientation after an additional clockwise rotation.
+func orientationRotate(o Orientation, quarterTurns int) Orientation {
+	transform := orientationTransforms[o]
+	return orientationFromTransform(transform[0], (transform[1]+quarterTurns)%4)
+}
+
+// orientationFlip returns the orientation after an additional horizontal or
+// vertical flip. A vertical flip is a horizontal flip rotated by 180 degrees.
+func orientationFlip(o Orientation, vertical bool) Orientation {
+	transform := orientationTransforms[o]
+
+	rotation := (4 - transform[1]) % 4
+	if vertical {
+		rotation = (rotation + 2) % 4
+	}
+
+	return orientationFromTransform(1-transform[0], rotation)
+}
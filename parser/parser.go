@@ -34,6 +34,39 @@ const (
 	GIF
 	WEBP
 	TIFF
+	AVIF
+	HEIC
+	HEIF
+	JXL
+	ICO
+	CUR
+	PSD
+	SVG
+	PBM
+	PGM
+	PPM
+	PAM
+	PFM
+	TGA
+	QOI
+	DDS
+	KTX
+	KTX2
+	EXR
+	HDR
+	JP2
+	JPX
+	J2K
+	DNG
+	CR2
+	NEF
+	ARW
+	ORF
+	RW2
+	PEF
+	CR3
+	RAF
+	APNG
 )
 
 func (t ImageType) String() string {
@@ -50,6 +83,72 @@ func (t ImageType) String() string {
 		return "WEBP"
 	case TIFF:
 		return "TIFF"
+	case AVIF:
+		return "AVIF"
+	case HEIC:
+		return "HEIC"
+	case HEIF:
+		return "HEIF"
+	case JXL:
+		return "JXL"
+	case ICO:
+		return "ICO"
+	case CUR:
+		return "CUR"
+	case PSD:
+		return "PSD"
+	case SVG:
+		return "SVG"
+	case PBM:
+		return "PBM"
+	case PGM:
+		return "PGM"
+	case PPM:
+		return "PPM"
+	case PAM:
+		return "PAM"
+	case PFM:
+		return "PFM"
+	case TGA:
+		return "TGA"
+	case QOI:
+		return "QOI"
+	case DDS:
+		return "DDS"
+	case KTX:
+		return "KTX"
+	case KTX2:
+		return "KTX2"
+	case EXR:
+		return "EXR"
+	case HDR:
+		return "HDR"
+	case JP2:
+		return "JP2"
+	case JPX:
+		return "JPX"
+	case J2K:
+		return "J2K"
+	case DNG:
+		return "DNG"
+	case CR2:
+		return "CR2"
+	case NEF:
+		return "NEF"
+	case ARW:
+		return "ARW"
+	case ORF:
+		return "ORF"
+	case RW2:
+		return "RW2"
+	case PEF:
+		return "PEF"
+	case CR3:
+		return "CR3"
+	case RAF:
+		return "RAF"
+	case APNG:
+		return "APNG"
 	case UnknownType:
 		return "UnknownType"
 	default:
@@ -71,6 +170,72 @@ func (t ImageType) ToMimetype() string {
 		return "image/webp"
 	case TIFF:
 		return "image/tiff"
+	case AVIF:
+		return "image/avif"
+	case HEIC:
+		return "image/heic"
+	case HEIF:
+		return "image/heif"
+	case JXL:
+		return "image/jxl"
+	case ICO:
+		return "image/vnd.microsoft.icon"
+	case CUR:
+		return "image/x-win-bitmap"
+	case PSD:
+		return "image/vnd.adobe.photoshop"
+	case SVG:
+		return "image/svg+xml"
+	case PBM:
+		return "image/x-portable-bitmap"
+	case PGM:
+		return "image/x-portable-graymap"
+	case PPM:
+		return "image/x-portable-pixmap"
+	case PAM:
+		return "image/x-portable-arbitrarymap"
+	case PFM:
+		return "image/x-portable-floatmap"
+	case TGA:
+		return "image/x-tga"
+	case QOI:
+		return "image/qoi"
+	case DDS:
+		return "image/vnd-ms.dds"
+	case KTX:
+		return "image/ktx"
+	case KTX2:
+		return "image/ktx2"
+	case EXR:
+		return "image/x-exr"
+	case HDR:
+		return "image/vnd.radiance"
+	case JP2:
+		return "image/jp2"
+	case JPX:
+		return "image/jpx"
+	case J2K:
+		return "image/j2c"
+	case DNG:
+		return "image/x-adobe-dng"
+	case CR2:
+		return "image/x-canon-cr2"
+	case NEF:
+		return "image/x-nikon-nef"
+	case ARW:
+		return "image/x-sony-arw"
+	case ORF:
+		return "image/x-olympus-orf"
+	case RW2:
+		return "image/x-panasonic-rw2"
+	case PEF:
+		return "image/x-pentax-pef"
+	case CR3:
+		return "image/x-canon-cr3"
+	case RAF:
+		return "image/x-fuji-raf"
+	case APNG:
+		return "image/apng"
 	case UnknownType:
 		return "application/octet-stream"
 	default:
@@ -89,6 +254,13 @@ type ImageParser interface {
 	GetSize(p []byte) (r Result, t ImageSize)
 }
 
+// Implemented by parsers of formats which other image types are based on, e.g. TIFF
+// for camera raw formats. DetectType accepts all of them, DetectSubtype returns the
+// image type of a file accepted by DetectType.
+type SubtypeParser interface {
+	DetectSubtype(p []byte) (r Result, t ImageType)
+}
+
 var ImageParsers = make(map[ImageType]ImageParser)
 
 func register(imageParser ImageParser) {
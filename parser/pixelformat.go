@@ -0,0 +1,64 @@
+package parser
+
+// Color model of the stored samples, before any conversion done by a decoder.
+type ColorModel uint8
+
+const (
+	ColorModelUnknown ColorModel = iota
+	ColorModelGray
+	ColorModelRGB
+	ColorModelPalette
+	ColorModelCMYK
+	ColorModelYCbCr
+)
+
+func (c ColorModel) String() string {
+	switch c {
+	case ColorModelGray:
+		return "Gray"
+	case ColorModelRGB:
+		return "RGB"
+	case ColorModelPalette:
+		return "Palette"
+	case ColorModelCMYK:
+		return "CMYK"
+	case ColorModelYCbCr:
+		return "YCbCr"
+	default:
+		return "Unknown"
+	}
+}
+
+type PixelFormat struct {
+	// Bits per sample of the color channels. Palette images report the size of the
+	// palette indexes.
+	BitDepth uint8
+
+	// Number of channels including alpha. Palette images have a single channel.
+	Channels uint16
+
+	ColorModel ColorModel
+
+	// Image has an alpha channel, or a transparent color for palette images and
+	// formats with a color key
+	Alpha bool
+}
+
+// Implemented by parsers of image formats whose header describes the pixel format.
+type PixelFormatParser interface {
+	GetPixelFormat(p []byte) (Result, PixelFormat)
+}
+
+// pixelFormatChannels returns the number of color channels of a color model.
+func pixelFormatChannels(colorModel ColorModel) uint16 {
+	switch colorModel {
+	case ColorModelGray, ColorModelPalette:
+		return 1
+	case ColorModelRGB, ColorModelYCbCr:
+		return 3
+	case ColorModelCMYK:
+		return 4
+	default:
+		return 0
+	}
+}
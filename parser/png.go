@@ -2,35 +2,232 @@ package parser
 
 import (
 	"bytes"
+	"compress/zlib"
 	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"time"
 )
 
-type PNGParser struct{}
+// pngParser implements the methods shared by the parsers of static and animated PNG
+// files. They accept both, the DetectType method of each parser tells them apart.
+type pngParser struct{}
+
+type PNGParser struct {
+	pngParser
+}
+
+// Animated PNG, https://wiki.mozilla.org/APNG_Specification
+type APNGParser struct {
+	pngParser
+}
 
 func (P PNGParser) Type() ImageType {
 	return PNG
 }
 
 func (P PNGParser) DetectType(p []byte) (r Result) {
+	return pngDetectType(p, PNG)
+}
+
+func (A APNGParser) Type() ImageType {
+	return APNG
+}
 
-	pngFileSignature := []byte{'\x89', 'P', 'N', 'G', '\x0D', '\x0A', '\x1A', '\x0A'}
+func (A APNGParser) DetectType(p []byte) (r Result) {
+	return pngDetectType(p, APNG)
+}
 
-	if len(p) < len(pngFileSignature) {
-		return NeedMoreData
+func (P pngParser) GetSize(p []byte) (r Result, t ImageSize) {
+	if result := P.detectType(p); result != Valid {
+		return result, ImageSize{}
 	}
 
-	if bytes.Equal(p[0:len(pngFileSignature)], pngFileSignature) {
-		return Valid
-	} else {
+	return pngGetSize(p)
+}
+
+// GetPixelFormat reads the IHDR chunk. Images without an alpha channel can still
+// have transparent colors, which are stored in the tRNS chunk in front of the image
+// data.
+func (P pngParser) GetPixelFormat(p []byte) (r Result, f PixelFormat) {
+	if result := P.detectType(p); result != Valid {
+		return result, PixelFormat{}
+	}
+
+	return pngGetPixelFormat(p)
+}
+
+// GetResolution reads the pixel density of the pHYs chunk.
+func (P pngParser) GetResolution(p []byte) (r Result, t Resolution) {
+	if result := P.detectType(p); result != Valid {
+		return result, Resolution{}
+	}
+
+	return pngGetResolution(p)
+}
+
+// GetColorProfile reads the ICC profile of the iCCP chunk and the color information
+// of the sRGB, gAMA, cHRM and cICP chunks.
+func (P pngParser) GetColorProfile(p []byte) (r Result, c ColorProfile) {
+	if result := P.detectType(p); result != Valid {
+		return result, ColorProfile{}
+	}
+
+	return pngGetColorProfile(p)
+}
+
+// GetOrientation reads the Orientation tag of the EXIF data stored in the eXIf chunk.
+func (P pngParser) GetOrientation(p []byte) (r Result, o Orientation) {
+	if result := P.detectType(p); result != Valid {
+		return result, OrientationNormal
+	}
+
+	return pngGetOrientation(p)
+}
+
+// GetInfoFromReaderAt reads the size, the pixel format and the resolution from the
+// start p of the file and the orientation from the eXIf chunk, whose chunk headers
+// are read directly from r to skip the image data in front of it.
+func (P pngParser) GetInfoFromReaderAt(p []byte, r io.ReaderAt, size int64) (res Result, i ReaderAtInfo) {
+	if result := P.detectType(p); result != Valid {
+		return result, ReaderAtInfo{}
+	}
+
+	result, imageSize := pngGetSize(p)
+	if result != Valid {
+		return result, ReaderAtInfo{}
+	}
+
+	result, pixelFormat := pngGetPixelFormat(p)
+	if result == NeedMoreData {
+		return NeedMoreData, ReaderAtInfo{}
+	}
+
+	result, resolution := pngGetResolution(p)
+	if result == NeedMoreData {
+		return NeedMoreData, ReaderAtInfo{}
+	}
+
+	info := ReaderAtInfo{Size: imageSize, Orientation: OrientationNormal, PixelFormat: pixelFormat, Resolution: resolution}
+
+	offset, length := pngFindChunkAt(r, size, "eXIf")
+	if offset == 0 {
+		return Valid, info
+	}
+
+	complete := true
+	if length > exifMaxOrientationSize {
+		length, complete = exifMaxOrientationSize, false
+	}
+
+	data := make([]byte, length)
+	if _, err := r.ReadAt(data, offset); err != nil && err != io.EOF {
+		return Valid, info
+	}
+
+	if result, orientation := exifOrientation(exifTrimIdentifier(data), complete); result == Valid {
+		info.Orientation = orientation
+	}
+
+	return Valid, info
+}
+
+// GetEXIF decodes the requested fields of the EXIF data stored in the eXIf chunk.
+func (P pngParser) GetEXIF(p []byte, fields EXIFFields) (r Result, e EXIF) {
+	if result := P.detectType(p); result != Valid {
+		return result, EXIF{}
+	}
+
+	return pngGetEXIF(p, fields)
+}
+
+// GetXMP returns the XMP packet stored in the iTXt chunk with the keyword
+// XML:com.adobe.xmp.
+func (P pngParser) GetXMP(p []byte) (r Result, x []byte) {
+	if result := P.detectType(p); result != Valid {
+		return result, nil
+	}
+
+	return pngGetXMP(p)
+}
+
+// GetAnimation reads the acTL chunk of APNG files, which has to be stored in front of
+// the image data, and the fcTL chunk in front of each frame. Only these chunks have
+// to be complete, the image data of the frames is skipped. Static PNG files are
+// reported as a single frame.
+func (P pngParser) GetAnimation(p []byte, maxFrames int) (r Result, a Animation) {
+	if result := P.detectType(p); result != Valid {
+		return result, Animation{}
+	}
+
+	return pngGetAnimation(p, maxFrames)
+}
+
+// detectType accepts both static and animated PNG files by their signature.
+func (P pngParser) detectType(p []byte) Result {
+	if len(p) < len(pngSignature) {
+		if bytes.Equal(p, pngSignature[:len(p)]) {
+			return NeedMoreData
+		}
 		return Invalid
 	}
+
+	if !bytes.Equal(p[:len(pngSignature)], pngSignature) {
+		return Invalid
+	}
+
+	return Valid
 }
 
-func (P PNGParser) GetSize(p []byte) (r Result, t ImageSize) {
-	if result := P.DetectType(p); result != Valid {
-		return result, ImageSize{}
+var pngSignature = []byte{'\x89', 'P', 'N', 'G', '\x0D', '\x0A', '\x1A', '\x0A'}
+
+// pngDetectType walks the chunks in front of the image data. Animated PNG files
+// store an acTL chunk there, which tells them apart from static ones.
+func pngDetectType(p []byte, expectedImageType ImageType) Result {
+	if len(p) < len(pngSignature) {
+		if bytes.Equal(p, pngSignature[:len(p)]) {
+			return NeedMoreData
+		}
+		return Invalid
+	}
+
+	if !bytes.Equal(p[:len(pngSignature)], pngSignature) {
+		return Invalid
+	}
+
+	imageType := UnknownType
+
+	for i := len(pngSignature); imageType == UnknownType; {
+		if len(p) < i+8 {
+			return NeedMoreData
+		}
+
+		chunkLength := int(binary.BigEndian.Uint32(p[i:]))
+
+		switch string(p[i+4 : i+8]) {
+		case "acTL":
+			imageType = APNG
+		case "IDAT", "IEND":
+			imageType = PNG
+		}
+
+		// Length, type, data and CRC, only the chunk headers have to be read
+		end := i + 8 + chunkLength + 4
+		if chunkLength < 0 || end < i {
+			return Invalid
+		}
+
+		i = end
+	}
+
+	if imageType != expectedImageType {
+		return Invalid
 	}
 
+	return Valid
+}
+
+func pngGetSize(p []byte) (Result, ImageSize) {
 	if len(p) < 24 {
 		return NeedMoreData, ImageSize{}
 	}
@@ -56,6 +253,408 @@ func (P PNGParser) GetSize(p []byte) (r Result, t ImageSize) {
 
 }
 
+func pngGetPixelFormat(p []byte) (Result, PixelFormat) {
+	result, ihdr := pngFindChunk(p, "IHDR", false)
+	if result != Valid {
+		return result, PixelFormat{}
+	}
+
+	if len(ihdr) < 13 {
+		return Invalid, PixelFormat{}
+	}
+
+	pixelFormat := PixelFormat{BitDepth: ihdr[8]}
+
+	switch colorType := ihdr[9]; colorType {
+	case 0:
+		pixelFormat.ColorModel = ColorModelGray
+	case 2:
+		pixelFormat.ColorModel = ColorModelRGB
+	case 3:
+		pixelFormat.ColorModel = ColorModelPalette
+	case 4:
+		pixelFormat.ColorModel = ColorModelGray
+		pixelFormat.Alpha = true
+	case 6:
+		pixelFormat.ColorModel = ColorModelRGB
+		pixelFormat.Alpha = true
+	default:
+		return Invalid, PixelFormat{}
+	}
+
+	pixelFormat.Channels = pixelFormatChannels(pixelFormat.ColorModel)
+	if pixelFormat.Alpha {
+		pixelFormat.Channels++
+		return Valid, pixelFormat
+	}
+
+	result, _ = pngFindChunk(p, "tRNS", false)
+	if result == NeedMoreData {
+		return NeedMoreData, PixelFormat{}
+	}
+
+	pixelFormat.Alpha = result == Valid
+
+	return Valid, pixelFormat
+}
+
+func pngGetResolution(p []byte) (Result, Resolution) {
+	result, data := pngFindChunk(p, "pHYs", false)
+	if result == NeedMoreData {
+		return NeedMoreData, Resolution{}
+	}
+
+	// Pixels per unit in both directions and the unit, which is either unknown or
+	// the meter
+	if result != Valid || len(data) < 9 {
+		return Valid, Resolution{}
+	}
+
+	x := binary.BigEndian.Uint32(data)
+	y := binary.BigEndian.Uint32(data[4:])
+	if x == 0 || y == 0 {
+		return Valid, Resolution{}
+	}
+
+	if data[8] != 1 {
+		return Valid, Resolution{X: float64(x), Y: float64(y), Unit: ResolutionUnitNone}
+	}
+
+	return Valid, resolutionPerMeter(x, y)
+}
+
+func pngGetColorProfile(p []byte) (Result, ColorProfile) {
+	colorProfile := ColorProfile{}
+	invalid := false
+
+	// Color chunks are stored in front of the image data
+	result := pngWalkChunks(p, []string{"iCCP", "sRGB", "gAMA", "cHRM", "cICP"}, false, func(chunkType string, data []byte) bool {
+		switch chunkType {
+		case "iCCP":
+			// Profile name, compression method and the compressed profile
+			fields := bytes.SplitN(data, []byte{0}, 2)
+			if len(fields) != 2 || len(fields[1]) < 1 || fields[1][0] != 0 {
+				invalid = true
+				return true
+			}
+
+			profile, ok := pngInflate(fields[1][1:])
+			if !ok {
+				invalid = true
+				return true
+			}
+
+			iccProfile := iccColorProfile(profile)
+			colorProfile.ICC = iccProfile.ICC
+			colorProfile.Description = iccProfile.Description
+			colorProfile.ColorSpace = iccProfile.ColorSpace
+		case "sRGB":
+			if len(data) >= 1 {
+				colorProfile.SRGB = true
+				colorProfile.RenderingIntent = data[0]
+			}
+		case "gAMA":
+			if len(data) >= 4 {
+				colorProfile.Gamma = float64(binary.BigEndian.Uint32(data)) / 100000
+			}
+		case "cHRM":
+			// White point, red, green and blue, each as x and y times 100000
+			if len(data) >= 32 {
+				chromaticity := func(i int) Chromaticity {
+					return Chromaticity{
+						X: float64(binary.BigEndian.Uint32(data[i:])) / 100000,
+						Y: float64(binary.BigEndian.Uint32(data[i+4:])) / 100000,
+					}
+				}
+
+				colorProfile.HasChromaticities = true
+				colorProfile.WhitePoint = chromaticity(0)
+				colorProfile.Red = chromaticity(8)
+				colorProfile.Green = chromaticity(16)
+				colorProfile.Blue = chromaticity(24)
+			}
+		case "cICP":
+			if len(data) >= 4 {
+				colorProfile.HasCICP = true
+				colorProfile.CICP = CICP{
+					ColorPrimaries:          uint16(data[0]),
+					TransferCharacteristics: uint16(data[1]),
+					MatrixCoefficients:      uint16(data[2]),
+					FullRange:               data[3] == 1,
+				}
+			}
+		}
+
+		return false
+	})
+
+	if result == NeedMoreData {
+		return NeedMoreData, ColorProfile{}
+	}
+
+	if invalid {
+		return Invalid, ColorProfile{}
+	}
+
+	return Valid, colorProfile
+}
+
+func pngGetOrientation(p []byte) (Result, Orientation) {
+	result, data := pngFindChunk(p, "eXIf", true)
+	if result == NeedMoreData {
+		return NeedMoreData, OrientationNormal
+	}
+
+	if result != Valid {
+		return Valid, OrientationNormal
+	}
+
+	result, orientation := exifOrientation(exifTrimIdentifier(data), true)
+	if result != Valid {
+		return Valid, OrientationNormal
+	}
+
+	return Valid, orientation
+}
+
+func pngGetEXIF(p []byte, fields EXIFFields) (Result, EXIF) {
+	result, data := pngFindChunk(p, "eXIf", true)
+	if result != Valid {
+		return result, EXIF{}
+	}
+
+	return exifDecode(exifTrimIdentifier(data), true, fields)
+}
+
+func pngGetXMP(p []byte) (Result, []byte) {
+	var packet []byte
+	invalid := false
+
+	// The XMP chunk is often added after the image data by metadata editors
+	result := pngWalkChunks(p, []string{"iTXt"}, true, func(_ string, data []byte) bool {
+		keyword, text, ok := pngParseITXt(data)
+		if keyword != "XML:com.adobe.xmp" {
+			return false
+		}
+
+		packet, invalid = text, !ok
+		return true
+	})
+
+	if result != Valid {
+		return result, nil
+	}
+
+	if invalid {
+		return Invalid, nil
+	}
+
+	return Valid, packet
+}
+
+func pngGetAnimation(p []byte, maxFrames int) (Result, Animation) {
+	animation := Animation{}
+
+	for i := 8; ; {
+		if len(p) < i+8 {
+			return NeedMoreData, Animation{}
+		}
+
+		chunkLength := int(binary.BigEndian.Uint32(p[i:]))
+		chunkType := string(p[i+4 : i+8])
+
+		// Length, type, data and CRC
+		end := i + 8 + chunkLength + 4
+		if chunkLength < 0 || end < i {
+			return Invalid, Animation{}
+		}
+
+		var data []byte
+		if chunkType == "acTL" || chunkType == "fcTL" {
+			if len(p) < end {
+				return NeedMoreData, Animation{}
+			}
+			data = p[i+8 : i+8+chunkLength]
+		}
+
+		switch chunkType {
+		case "IDAT":
+			if !animation.Animated {
+				return Valid, Animation{Frames: 1}
+			}
+		case "IEND":
+			return Valid, animation
+		case "acTL":
+			// Number of frames and number of plays
+			if len(data) < 8 {
+				return Invalid, Animation{}
+			}
+
+			animation.Animated = binary.BigEndian.Uint32(data) > 1
+			animation.Loops = int(binary.BigEndian.Uint32(data[4:]))
+		case "fcTL":
+			// Sequence number, size, offset and the delay as a fraction of seconds,
+			// where a denominator of 0 means hundredths
+			if len(data) < 26 {
+				return Invalid, Animation{}
+			}
+
+			numerator := time.Duration(binary.BigEndian.Uint16(data[20:]))
+			denominator := time.Duration(binary.BigEndian.Uint16(data[22:]))
+			if denominator == 0 {
+				denominator = 100
+			}
+
+			if animationAddFrame(&animation, numerator*time.Second/denominator, maxFrames) {
+				return Valid, animation
+			}
+		}
+
+		i = end
+	}
+}
+
+// pngFindChunk returns the data of the first chunk of the given type. Only the
+// chunks in front of the image data are searched, unless afterImageData is set.
+func pngFindChunk(p []byte, chunkType string, afterImageData bool) (Result, []byte) {
+	var data []byte
+
+	result := pngWalkChunks(p, []string{chunkType}, afterImageData, func(_ string, chunkData []byte) bool {
+		data = chunkData
+		return true
+	})
+
+	return result, data
+}
+
+// pngWalkChunks calls fn with the type and data of every chunk of the given types,
+// until fn returns true. Other chunks are skipped by their header, so only the data
+// of these chunks has to be read. The walk stops at the image data, or at the IEND
+// chunk if afterImageData is set, as metadata chunks may also follow the image data.
+// Invalid is returned if the walk stops before fn returns true.
+func pngWalkChunks(p []byte, chunkTypes []string, afterImageData bool, fn func(chunkType string, data []byte) bool) Result {
+	for i := 8; ; {
+		if len(p) < i+8 {
+			return NeedMoreData
+		}
+
+		chunkLength := int(binary.BigEndian.Uint32(p[i:]))
+		chunkType := string(p[i+4 : i+8])
+
+		if chunkType == "IEND" || (chunkType == "IDAT" && !afterImageData) {
+			return Invalid
+		}
+
+		// Length, type, data and CRC
+		end := i + 8 + chunkLength + 4
+		if chunkLength < 0 || end < i {
+			return Invalid
+		}
+
+		for _, wantedType := range chunkTypes {
+			if chunkType != wantedType {
+				continue
+			}
+
+			if len(p) < i+8+chunkLength {
+				return NeedMoreData
+			}
+
+			if fn(chunkType, p[i+8:i+8+chunkLength]) {
+				return Valid
+			}
+		}
+
+		i = end
+	}
+}
+
+// pngFindChunkAt returns the offset and length of the data of the first chunk of the
+// given type, reading only the chunk headers from r up to the IEND chunk. Zero is
+// returned if there is no such chunk.
+func pngFindChunkAt(r io.ReaderAt, size int64, chunkType string) (int64, int64) {
+	header := make([]byte, 8)
+
+	for i := int64(8); i+8 <= size; {
+		if _, err := r.ReadAt(header, i); err != nil {
+			return 0, 0
+		}
+
+		chunkLength := int64(binary.BigEndian.Uint32(header))
+		currentType := string(header[4:])
+
+		if currentType == "IEND" {
+			return 0, 0
+		}
+
+		if currentType == chunkType {
+			if i+8+chunkLength > size {
+				return 0, 0
+			}
+
+			return i + 8, chunkLength
+		}
+
+		// Length, type, data and CRC
+		i += 8 + chunkLength + 4
+	}
+
+	return 0, 0
+}
+
+// Upper limit for the size of decompressed chunk data
+const pngMaxInflatedSize = 16 << 20
+
+// pngParseITXt returns the keyword and the text of an iTXt chunk, which might be
+// compressed.
+func pngParseITXt(data []byte) (string, []byte, bool) {
+	// Keyword, compression flag and method, language tag and translated keyword
+	fields := bytes.SplitN(data, []byte{0}, 2)
+	if len(fields) != 2 {
+		return "", nil, false
+	}
+
+	keyword, rest := string(fields[0]), fields[1]
+	if len(rest) < 2 {
+		return keyword, nil, false
+	}
+
+	compressed := rest[0] == 1
+	rest = rest[2:]
+
+	for j := 0; j < 2; j++ {
+		end := bytes.IndexByte(rest, 0)
+		if end == -1 {
+			return keyword, nil, false
+		}
+		rest = rest[end+1:]
+	}
+
+	if !compressed {
+		return keyword, rest, true
+	}
+
+	text, ok := pngInflate(rest)
+	return keyword, text, ok
+}
+
+// pngInflate decompresses the zlib stream of text and color profile chunks.
+func pngInflate(data []byte) ([]byte, bool) {
+	reader, err := zlib.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, false
+	}
+	defer reader.Close()
+
+	inflated, err := ioutil.ReadAll(io.LimitReader(reader, pngMaxInflatedSize))
+	if err != nil {
+		return nil, false
+	}
+
+	return inflated, true
+}
+
 func init() {
 	register(&PNGParser{})
+	register(&APNGParser{})
 }
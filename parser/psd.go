@@ -0,0 +1,238 @@
+package parser
+
+import (
+	"bytes"
+	"encoding/binary"
+)
+
+// https://www.adobe.com/devnet-apps/photoshop/fileformatashtml/
+
+type PSDParser struct{}
+
+type PSDColorMode uint16
+
+const (
+	PSDBitmap       PSDColorMode = 0
+	PSDGrayscale    PSDColorMode = 1
+	PSDIndexed      PSDColorMode = 2
+	PSDRGB          PSDColorMode = 3
+	PSDCMYK         PSDColorMode = 4
+	PSDMultichannel PSDColorMode = 7
+	PSDDuotone      PSDColorMode = 8
+	PSDLab          PSDColorMode = 9
+)
+
+func (m PSDColorMode) String() string {
+	switch m {
+	case PSDBitmap:
+		return "Bitmap"
+	case PSDGrayscale:
+		return "Grayscale"
+	case PSDIndexed:
+		return "Indexed"
+	case PSDRGB:
+		return "RGB"
+	case PSDCMYK:
+		return "CMYK"
+	case PSDMultichannel:
+		return "Multichannel"
+	case PSDDuotone:
+		return "Duotone"
+	case PSDLab:
+		return "Lab"
+	default:
+		return "Unknown"
+	}
+}
+
+type PSDHeader struct {
+	// Version 1 is a PSD file, version 2 a PSB (large document format) file
+	Version uint16
+
+	Size      ImageSize
+	Channels  uint16
+	Depth     uint16
+	ColorMode PSDColorMode
+}
+
+func (P PSDParser) Type() ImageType {
+	return PSD
+}
+
+func (P PSDParser) DetectType(p []byte) (r Result) {
+	psdSignature := []byte{'8', 'B', 'P', 'S'}
+
+	if len(p) < len(psdSignature)+2 {
+		return NeedMoreData
+	}
+
+	if !bytes.Equal(p[:len(psdSignature)], psdSignature) {
+		return Invalid
+	}
+
+	version := binary.BigEndian.Uint16(p[4:])
+	if version != 1 && version != 2 {
+		return Invalid
+	}
+
+	return Valid
+}
+
+func (P PSDParser) GetSize(p []byte) (r Result, t ImageSize) {
+	result, header := P.GetHeader(p)
+	if result != Valid {
+		return result, ImageSize{}
+	}
+
+	return Valid, header.Size
+}
+
+// GetHeader reads the 26 byte file header.
+func (P PSDParser) GetHeader(p []byte) (r Result, h PSDHeader) {
+	if result := P.DetectType(p); result != Valid {
+		return result, PSDHeader{}
+	}
+
+	if len(p) < 26 {
+		return NeedMoreData, PSDHeader{}
+	}
+
+	// Signature (4), version (2) and 6 reserved bytes
+	header := PSDHeader{
+		Version:   binary.BigEndian.Uint16(p[4:]),
+		Channels:  binary.BigEndian.Uint16(p[12:]),
+		Depth:     binary.BigEndian.Uint16(p[22:]),
+		ColorMode: PSDColorMode(binary.BigEndian.Uint16(p[24:])),
+	}
+
+	header.Size.Height = binary.BigEndian.Uint32(p[14:])
+	header.Size.Width = binary.BigEndian.Uint32(p[18:])
+
+	return Valid, header
+}
+
+// GetPixelFormat derives the pixel format from the header. Channels beyond the color
+// channels are reported as alpha, as the first of them holds the transparency of
+// the merged image.
+func (P PSDParser) GetPixelFormat(p []byte) (r Result, f PixelFormat) {
+	result, header := P.GetHeader(p)
+	if result != Valid {
+		return result, PixelFormat{}
+	}
+
+	pixelFormat := PixelFormat{
+		BitDepth: uint8(header.Depth),
+		Channels: header.Channels,
+	}
+
+	switch header.ColorMode {
+	case PSDBitmap, PSDGrayscale, PSDDuotone:
+		pixelFormat.ColorModel = ColorModelGray
+	case PSDIndexed:
+		pixelFormat.ColorModel = ColorModelPalette
+	case PSDRGB:
+		pixelFormat.ColorModel = ColorModelRGB
+	case PSDCMYK:
+		pixelFormat.ColorModel = ColorModelCMYK
+	}
+
+	colorChannels := pixelFormatChannels(pixelFormat.ColorModel)
+	pixelFormat.Alpha = colorChannels > 0 && header.Channels > colorChannels
+
+	return Valid, pixelFormat
+}
+
+// GetResolution reads the ResolutionInfo image resource. Its resolution is stored in
+// pixels per inch, independent of the unit Photoshop displays.
+func (P PSDParser) GetResolution(p []byte) (r Result, t Resolution) {
+	if result := P.DetectType(p); result != Valid {
+		return result, Resolution{}
+	}
+
+	result, data := psdFindResource(p, 0x03ed)
+	if result == NeedMoreData {
+		return NeedMoreData, Resolution{}
+	}
+
+	// Horizontal resolution as 16.16 fixed point number, its display unit and the
+	// display unit of the width, followed by the same fields for the vertical axis
+	if result != Valid || len(data) < 16 {
+		return Valid, Resolution{}
+	}
+
+	x := float64(binary.BigEndian.Uint32(data)) / 65536
+	y := float64(binary.BigEndian.Uint32(data[8:])) / 65536
+	if x == 0 || y == 0 {
+		return Valid, Resolution{}
+	}
+
+	return Valid, Resolution{X: x, Y: y, Unit: ResolutionUnitInch}
+}
+
+// psdFindResource returns the data of the image resource with the given id. The
+// image resources section follows the header and the color mode data section.
+func psdFindResource(p []byte, id uint16) (Result, []byte) {
+	if len(p) < 30 {
+		return NeedMoreData, nil
+	}
+
+	i := 30 + int(binary.BigEndian.Uint32(p[26:]))
+	if i < 30 {
+		return Invalid, nil
+	}
+
+	if len(p) < i+4 {
+		return NeedMoreData, nil
+	}
+
+	end := i + 4 + int(binary.BigEndian.Uint32(p[i:]))
+	if end < i {
+		return Invalid, nil
+	}
+
+	for i += 4; i < end; {
+		// Signature, id and the length of the name
+		if len(p) < i+7 {
+			return NeedMoreData, nil
+		}
+
+		if !bytes.Equal(p[i:i+4], []byte("8BIM")) {
+			return Invalid, nil
+		}
+
+		resourceID := binary.BigEndian.Uint16(p[i+4:])
+
+		// Pascal string padded to an even size
+		nameLength := int(p[i+6]) + 1
+		nameLength += nameLength % 2
+
+		j := i + 6 + nameLength
+		if len(p) < j+4 {
+			return NeedMoreData, nil
+		}
+
+		size := int(binary.BigEndian.Uint32(p[j:]))
+
+		// Resource data padded to an even size
+		next := j + 4 + size + size%2
+		if size < 0 || next < j || next > end {
+			return Invalid, nil
+		}
+
+		if resourceID == id {
+			if len(p) < j+4+size {
+				return NeedMoreData, nil
+			}
+
+			return Valid, p[j+4 : j+4+size]
+		}
+
+		i = next
+	}
+
+	return Invalid, nil
+}
+
+func init() {
+	register(&PSDParser{})
+}
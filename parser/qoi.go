@@ -0,0 +1,96 @@
+package parser
+
+import (
+	"bytes"
+	"encoding/binary"
+)
+
+// https://qoiformat.org/qoi-specification.pdf
+
+type QOIParser struct{}
+
+type QOIHeader struct {
+	Size ImageSize
+
+	// 3 = RGB, 4 = RGBA
+	Channels uint8
+
+	// 0 = sRGB with linear alpha, 1 = all channels linear
+	Colorspace uint8
+}
+
+func (Q QOIParser) Type() ImageType {
+	return QOI
+}
+
+func (Q QOIParser) DetectType(p []byte) (r Result) {
+	qoiMagic := []byte{'q', 'o', 'i', 'f'}
+
+	if len(p) < len(qoiMagic) {
+		return NeedMoreData
+	}
+
+	if bytes.Equal(p[0:len(qoiMagic)], qoiMagic) {
+		return Valid
+	} else {
+		return Invalid
+	}
+}
+
+func (Q QOIParser) GetSize(p []byte) (r Result, t ImageSize) {
+	result, header := Q.GetHeader(p)
+	if result != Valid {
+		return result, ImageSize{}
+	}
+
+	return Valid, header.Size
+}
+
+// GetHeader reads the 14 byte file header.
+func (Q QOIParser) GetHeader(p []byte) (r Result, h QOIHeader) {
+	if result := Q.DetectType(p); result != Valid {
+		return result, QOIHeader{}
+	}
+
+	if len(p) < 14 {
+		return NeedMoreData, QOIHeader{}
+	}
+
+	header := QOIHeader{
+		Channels:   p[12],
+		Colorspace: p[13],
+	}
+
+	header.Size.Width = binary.BigEndian.Uint32(p[4:])
+	header.Size.Height = binary.BigEndian.Uint32(p[8:])
+
+	if header.Channels != 3 && header.Channels != 4 {
+		return Invalid, QOIHeader{}
+	}
+
+	if header.Colorspace > 1 {
+		return Invalid, QOIHeader{}
+	}
+
+	return Valid, header
+}
+
+func (Q QOIParser) GetPixelFormat(p []byte) (r Result, f PixelFormat) {
+	result, header := Q.GetHeader(p)
+	if result != Valid {
+		return result, PixelFormat{}
+	}
+
+	pixelFormat := PixelFormat{
+		BitDepth:   8,
+		Channels:   uint16(header.Channels),
+		ColorModel: ColorModelRGB,
+		Alpha:      header.Channels == 4,
+	}
+
+	return Valid, pixelFormat
+}
+
+func init() {
+	register(&QOIParser{})
+}
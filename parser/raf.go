@@ -0,0 +1,233 @@
+package parser
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+)
+
+// Information about the raf structure can be found here:
+// https://libopenraw.freedesktop.org/formats/raf/
+// https://exiftool.org/TagNames/FujiFilm.html#RAF
+
+type RAFParser struct{}
+
+func (R RAFParser) Type() ImageType {
+	return RAF
+}
+
+func (R RAFParser) DetectType(p []byte) (r Result) {
+	rafMagic := []byte("FUJIFILMCCD-RAW ")
+
+	if len(p) < len(rafMagic) {
+		return NeedMoreData
+	}
+
+	if bytes.Equal(p[0:len(rafMagic)], rafMagic) {
+		return Valid
+	} else {
+		return Invalid
+	}
+}
+
+// GetSize reads the sensor size from the CFA header. The CFA header follows the jpeg
+// preview, so the data up to its end is needed even though the preview is not parsed.
+// GetInfoFromReaderAt reads only the CFA header if random access is possible.
+func (R RAFParser) GetSize(p []byte) (r Result, t ImageSize) {
+	if result := R.DetectType(p); result != Valid {
+		return result, ImageSize{}
+	}
+
+	if len(p) < 108 {
+		return NeedMoreData, ImageSize{}
+	}
+
+	return rafSensorSize(p)
+}
+
+// Upper limit of the CFA header size, which usually holds less than 30 records
+const rafMaxCFAHeaderSize = 64 * 1024
+
+// GetInfoFromReaderAt reads the sensor size like GetSize, but reads the records of
+// the CFA header directly from r at the offset stored in the RAF header, which skips
+// the jpeg preview.
+func (R RAFParser) GetInfoFromReaderAt(p []byte, r io.ReaderAt, size int64) (res Result, i ReaderAtInfo) {
+	if result := R.DetectType(p); result != Valid {
+		return result, ReaderAtInfo{}
+	}
+
+	if len(p) < 108 {
+		return NeedMoreData, ReaderAtInfo{}
+	}
+
+	// Offset and length of the CFA header
+	offset := int64(binary.BigEndian.Uint32(p[92:]))
+	length := int64(binary.BigEndian.Uint32(p[96:]))
+
+	if offset < 108 || length < 4 || length > rafMaxCFAHeaderSize || size < offset+length {
+		return Invalid, ReaderAtInfo{}
+	}
+
+	result, imageSize := rafReadCFAHeader(r, offset, length)
+	if result != Valid {
+		return Invalid, ReaderAtInfo{}
+	}
+
+	return Valid, ReaderAtInfo{Size: imageSize, Orientation: OrientationNormal}
+}
+
+// GetSizes returns the sensor size and the size of the embedded jpeg preview.
+func (R RAFParser) GetSizes(p []byte) (r Result, s RAWSizes) {
+	if result := R.DetectType(p); result != Valid {
+		return result, RAWSizes{}
+	}
+
+	if len(p) < 108 {
+		return NeedMoreData, RAWSizes{}
+	}
+
+	sizes := RAWSizes{}
+
+	// Offset and length of the jpeg preview
+	jpegOffset := int(binary.BigEndian.Uint32(p[84:]))
+	jpegLength := int(binary.BigEndian.Uint32(p[88:]))
+
+	if jpegLength > 0 {
+		if jpegOffset < 108 || len(p) < jpegOffset {
+			return NeedMoreData, RAWSizes{}
+		}
+
+		result, size := JPEGParser{}.GetSize(p[jpegOffset:])
+		if result != Valid {
+			return result, RAWSizes{}
+		}
+		sizes.Preview = size
+	}
+
+	result, size := rafSensorSize(p)
+	if result != Valid {
+		return result, RAWSizes{}
+	}
+	sizes.Sensor = size
+
+	return Valid, sizes
+}
+
+// rafSensorSize reads the records of the CFA header, which follows the jpeg preview.
+func rafSensorSize(p []byte) (Result, ImageSize) {
+	i := int(binary.BigEndian.Uint32(p[92:]))
+	if i < 108 {
+		return Invalid, ImageSize{}
+	}
+
+	if len(p) < i+4 {
+		return NeedMoreData, ImageSize{}
+	}
+
+	recordCount := int(binary.BigEndian.Uint32(p[i:]))
+	i += 4
+
+	var croppedSize ImageSize
+
+	for j := 0; j < recordCount; j++ {
+		if len(p) < i+4 {
+			return NeedMoreData, ImageSize{}
+		}
+
+		tag := binary.BigEndian.Uint16(p[i:])
+		size := int(binary.BigEndian.Uint16(p[i+2:]))
+		i += 4
+
+		if len(p) < i+size {
+			return NeedMoreData, ImageSize{}
+		}
+
+		// Sizes are stored as height followed by width
+		if size == 4 {
+			value := ImageSize{
+				Width:  uint32(binary.BigEndian.Uint16(p[i+2:])),
+				Height: uint32(binary.BigEndian.Uint16(p[i:])),
+			}
+
+			switch tag {
+			case 0x100:
+				// RawImageFullSize
+				return Valid, value
+			case 0x111:
+				// RawImageCroppedSize
+				croppedSize = value
+			}
+		}
+
+		i += size
+	}
+
+	if croppedSize.Width == 0 || croppedSize.Height == 0 {
+		return Invalid, ImageSize{}
+	}
+
+	return Valid, croppedSize
+}
+
+// rafReadCFAHeader reads the full or the cropped sensor size from the CFA header at
+// the given offset of r. Only the record headers and the values of the size records
+// are read.
+func rafReadCFAHeader(r io.ReaderAt, offset int64, length int64) (Result, ImageSize) {
+	buf := make([]byte, 4)
+	end := offset + length
+
+	if _, err := r.ReadAt(buf, offset); err != nil {
+		return Invalid, ImageSize{}
+	}
+
+	recordCount := int(binary.BigEndian.Uint32(buf))
+	i := offset + 4
+
+	var croppedSize ImageSize
+
+	for j := 0; j < recordCount && i+4 <= end; j++ {
+		if _, err := r.ReadAt(buf, i); err != nil {
+			return Invalid, ImageSize{}
+		}
+
+		tag := binary.BigEndian.Uint16(buf)
+		size := int64(binary.BigEndian.Uint16(buf[2:]))
+		i += 4
+
+		if end < i+size {
+			return Invalid, ImageSize{}
+		}
+
+		// Sizes are stored as height followed by width
+		if size == 4 && (tag == 0x100 || tag == 0x111) {
+			if _, err := r.ReadAt(buf, i); err != nil {
+				return Invalid, ImageSize{}
+			}
+
+			value := ImageSize{
+				Width:  uint32(binary.BigEndian.Uint16(buf[2:])),
+				Height: uint32(binary.BigEndian.Uint16(buf)),
+			}
+
+			if tag == 0x100 {
+				// RawImageFullSize
+				return Valid, value
+			}
+
+			// RawImageCroppedSize
+			croppedSize = value
+		}
+
+		i += size
+	}
+
+	if croppedSize.Width == 0 || croppedSize.Height == 0 {
+		return Invalid, ImageSize{}
+	}
+
+	return Valid, croppedSize
+}
+
+func init() {
+	register(&RAFParser{})
+}
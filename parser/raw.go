@@ -0,0 +1,363 @@
+package parser
+
+import "encoding/binary"
+
+// Camera raw formats based on TIFF. Information about their structure can be found here:
+// https://helpx.adobe.com/camera-raw/digital-negative.html
+// https://github.com/lclevy/libcraw2/blob/master/docs/cr2_poster.pdf
+// https://exiftool.org/TagNames/PanasonicRaw.html
+// https://www.libraw.org/docs
+
+type DNGParser struct{}
+
+type CR2Parser struct{}
+
+type NEFParser struct{}
+
+type ARWParser struct{}
+
+type ORFParser struct{}
+
+type RW2Parser struct{}
+
+type PEFParser struct{}
+
+// Sizes reported by raw formats with a separately stored preview image
+type RAWSizes struct {
+	// Size of the raw sensor image
+	Sensor ImageSize
+
+	// Size of the embedded preview image, zero if there is none
+	Preview ImageSize
+}
+
+func (R DNGParser) Type() ImageType {
+	return DNG
+}
+
+func (R DNGParser) DetectType(p []byte) (r Result) {
+	return rawDetectType(p, DNG)
+}
+
+func (R DNGParser) GetSize(p []byte) (r Result, t ImageSize) {
+	return rawGetSize(p, DNG)
+}
+
+func (R CR2Parser) Type() ImageType {
+	return CR2
+}
+
+func (R CR2Parser) DetectType(p []byte) (r Result) {
+	return rawDetectType(p, CR2)
+}
+
+func (R CR2Parser) GetSize(p []byte) (r Result, t ImageSize) {
+	return rawGetSize(p, CR2)
+}
+
+func (R NEFParser) Type() ImageType {
+	return NEF
+}
+
+func (R NEFParser) DetectType(p []byte) (r Result) {
+	return rawDetectType(p, NEF)
+}
+
+func (R NEFParser) GetSize(p []byte) (r Result, t ImageSize) {
+	return rawGetSize(p, NEF)
+}
+
+func (R ARWParser) Type() ImageType {
+	return ARW
+}
+
+func (R ARWParser) DetectType(p []byte) (r Result) {
+	return rawDetectType(p, ARW)
+}
+
+func (R ARWParser) GetSize(p []byte) (r Result, t ImageSize) {
+	return rawGetSize(p, ARW)
+}
+
+func (R ORFParser) Type() ImageType {
+	return ORF
+}
+
+func (R ORFParser) DetectType(p []byte) (r Result) {
+	return rawDetectType(p, ORF)
+}
+
+func (R ORFParser) GetSize(p []byte) (r Result, t ImageSize) {
+	return rawGetSize(p, ORF)
+}
+
+func (R RW2Parser) Type() ImageType {
+	return RW2
+}
+
+func (R RW2Parser) DetectType(p []byte) (r Result) {
+	return rawDetectType(p, RW2)
+}
+
+func (R RW2Parser) GetSize(p []byte) (r Result, t ImageSize) {
+	return rawGetSize(p, RW2)
+}
+
+func (R PEFParser) Type() ImageType {
+	return PEF
+}
+
+func (R PEFParser) DetectType(p []byte) (r Result) {
+	return rawDetectType(p, PEF)
+}
+
+func (R PEFParser) GetSize(p []byte) (r Result, t ImageSize) {
+	return rawGetSize(p, PEF)
+}
+
+func rawDetectType(p []byte, expectedImageType ImageType) Result {
+	result, imageType := tiffImageType(p)
+	if result != Valid {
+		return result
+	}
+
+	if imageType != expectedImageType {
+		return Invalid
+	}
+
+	return Valid
+}
+
+// rawGetSize returns the size of the full resolution raw image. Raw files usually
+// contain previews as well, which are often stored in the first IFD. CR2 and RW2
+// files store the sensor size in their own way.
+func rawGetSize(p []byte, imageType ImageType) (Result, ImageSize) {
+	if result := rawDetectType(p, imageType); result != Valid {
+		return result, ImageSize{}
+	}
+
+	_, header := tiffReadHeader(p)
+	byteOrder := header.byteOrder
+
+	if imageType == RW2 {
+		return rw2GetSize(p, header)
+	}
+
+	if imageType == CR2 {
+		return cr2GetSize(p, header)
+	}
+
+	// Walk the IFD chain and all SubIFDs
+	var largest, largestFullResolution ImageSize
+
+	result, ifds := tiffReadIFDs(p, header)
+	if result != Valid {
+		return result, ImageSize{}
+	}
+
+	for _, ifd := range ifds {
+		result, size := tiffIFDSize(p, byteOrder, ifd)
+		if result == NeedMoreData {
+			return NeedMoreData, ImageSize{}
+		}
+
+		if result != Valid {
+			continue
+		}
+
+		// NewSubfileType, bit 0 is set for reduced resolution images
+		newSubfileType := 0
+		if result, value := tiffEntryValue(p, byteOrder, ifd, 254); result == Valid {
+			newSubfileType = value
+		}
+
+		if rawArea(size) > rawArea(largest) {
+			largest = size
+		}
+
+		if newSubfileType&1 == 0 && rawArea(size) > rawArea(largestFullResolution) {
+			largestFullResolution = size
+		}
+	}
+
+	if rawArea(largestFullResolution) > 0 {
+		return Valid, largestFullResolution
+	}
+
+	if rawArea(largest) > 0 {
+		return Valid, largest
+	}
+
+	return Invalid, ImageSize{}
+}
+
+// rw2GetSize returns the sensor size, which Panasonic stores in its own tags of the
+// first IFD instead of ImageWidth and ImageLength.
+func rw2GetSize(p []byte, header tiffHeader) (Result, ImageSize) {
+	byteOrder := header.byteOrder
+
+	result, ifd := tiffReadIFD(p, header, header.offsetFirstIFD)
+	if result != Valid {
+		return result, ImageSize{}
+	}
+
+	// SensorWidth
+	result, width := tiffEntryValue(p, byteOrder, ifd, 2)
+	if result != Valid {
+		return result, ImageSize{}
+	}
+
+	// SensorHeight
+	result, height := tiffEntryValue(p, byteOrder, ifd, 3)
+	if result != Valid {
+		return result, ImageSize{}
+	}
+
+	return Valid, ImageSize{Width: uint32(width), Height: uint32(height)}
+}
+
+// cr2GetSize returns the sensor size of the raw image, which is stored in the fourth
+// IFD. The first IFD holds the full size JPEG preview. As the raw IFD has no
+// ImageWidth and ImageLength, the size is read from the SensorInfo tag of the Canon
+// maker note or from the header of the lossless JPEG holding the raw data.
+func cr2GetSize(p []byte, header tiffHeader) (Result, ImageSize) {
+	var ifd0, ifd tiffIFD
+
+	offset := header.offsetFirstIFD
+	for j := 0; j < 4; j++ {
+		result, current := tiffReadIFD(p, header, offset)
+		if result != Valid {
+			return result, ImageSize{}
+		}
+
+		if j == 0 {
+			ifd0 = current
+		}
+
+		ifd, offset = current, current.next
+	}
+
+	// Lossless JPEG compression and the slice layout of the raw data
+	result, compression := tiffEntryValue(p, header.byteOrder, ifd, 259)
+	if result != Valid {
+		return result, ImageSize{}
+	}
+
+	if _, ok := ifd.entries[50752]; !ok || compression != 6 {
+		return Invalid, ImageSize{}
+	}
+
+	result, size := cr2SensorInfoSize(p, header, ifd0)
+	if result != Invalid {
+		return result, size
+	}
+
+	return cr2LosslessJPEGSize(p, header.byteOrder, ifd)
+}
+
+// cr2SensorInfoSize reads SensorWidth and SensorHeight of the SensorInfo tag, which
+// is stored in the maker note of the Exif IFD. The maker note is an IFD whose
+// offsets are relative to the start of the file.
+func cr2SensorInfoSize(p []byte, header tiffHeader, ifd0 tiffIFD) (Result, ImageSize) {
+	result, offset := tiffEntryValue(p, header.byteOrder, ifd0, exifTagExifIFD)
+	if result != Valid {
+		return result, ImageSize{}
+	}
+
+	result, exifIFD := tiffReadIFD(p, header, offset)
+	if result != Valid {
+		return result, ImageSize{}
+	}
+
+	// MakerNote
+	entry, ok := exifIFD.entries[37500]
+	if !ok {
+		return Invalid, ImageSize{}
+	}
+
+	result, makerNote := tiffReadIFD(p, header, entry.valueOffset)
+	if result != Valid {
+		return result, ImageSize{}
+	}
+
+	// SensorInfo
+	entry, ok = makerNote.entries[0xe0]
+	if !ok {
+		return Invalid, ImageSize{}
+	}
+
+	result, values := tiffEntryUints(p, header.byteOrder, entry)
+	if result != Valid {
+		return result, ImageSize{}
+	}
+
+	if len(values) < 3 || values[1] == 0 || values[2] == 0 {
+		return Invalid, ImageSize{}
+	}
+
+	return Valid, ImageSize{Width: uint32(values[1]), Height: uint32(values[2])}
+}
+
+// cr2LosslessJPEGSize reads the SOF3 segment of the lossless JPEG stored at the
+// strip offset of the raw IFD. The width of the raw image is the number of samples
+// per line times the number of components.
+func cr2LosslessJPEGSize(p []byte, byteOrder TIFFByteOrder, ifd tiffIFD) (Result, ImageSize) {
+	result, offset := tiffEntryValue(p, byteOrder, ifd, 273)
+	if result != Valid {
+		return result, ImageSize{}
+	}
+
+	if len(p) < offset+2 {
+		return NeedMoreData, ImageSize{}
+	}
+
+	// Start of image, followed by the segments in front of the scan
+	if p[offset] != 0xff || p[offset+1] != 0xd8 {
+		return Invalid, ImageSize{}
+	}
+
+	for i := offset + 2; ; {
+		if len(p) < i+4 {
+			return NeedMoreData, ImageSize{}
+		}
+
+		marker := p[i+1]
+		length := int(binary.BigEndian.Uint16(p[i+2:]))
+
+		if p[i] != 0xff || marker == 0xda || marker == 0xd9 || length < 2 {
+			return Invalid, ImageSize{}
+		}
+
+		if marker == 0xc3 {
+			// Precision, lines, samples per line and components
+			if len(p) < i+10 {
+				return NeedMoreData, ImageSize{}
+			}
+
+			height := uint32(binary.BigEndian.Uint16(p[i+5:]))
+			width := uint32(binary.BigEndian.Uint16(p[i+7:])) * uint32(p[i+9])
+
+			if width == 0 || height == 0 {
+				return Invalid, ImageSize{}
+			}
+
+			return Valid, ImageSize{Width: width, Height: height}
+		}
+
+		i += 2 + length
+	}
+}
+
+func rawArea(size ImageSize) uint64 {
+	return uint64(size.Width) * uint64(size.Height)
+}
+
+func init() {
+	register(&DNGParser{})
+	register(&CR2Parser{})
+	register(&NEFParser{})
+	register(&ARWParser{})
+	register(&ORFParser{})
+	register(&RW2Parser{})
+	register(&PEFParser{})
+}
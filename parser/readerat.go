@@ -0,0 +1,27 @@
+package parser
+
+import "io"
+
+// Image info read by parsers of the ReaderAtSizeParser interface
+type ReaderAtInfo struct {
+	Size        ImageSize
+	Orientation Orientation
+	PixelFormat PixelFormat
+	Resolution  Resolution
+}
+
+// Implemented by parsers of formats which store parts of the image info far from the
+// start of the file, e.g. behind a large preview or the image data. If random access
+// to the file is possible, these parts are read directly from r, which holds size
+// bytes. p holds the data read from the start of the file so far. NeedMoreData is
+// returned if p does not cover the header yet, Invalid if the parts can not be read
+// this way.
+type ReaderAtSizeParser interface {
+	GetInfoFromReaderAt(p []byte, r io.ReaderAt, size int64) (res Result, i ReaderAtInfo)
+}
+
+// Implemented by parsers of formats which can be detected by a footer, as a fallback
+// for files whose start is not recognized. r holds size bytes.
+type FooterParser interface {
+	DetectFooter(r io.ReaderAt, size int64) (res Result)
+}
@@ -0,0 +1,57 @@
+package parser
+
+// Unit of the pixel density stored in an image.
+type ResolutionUnit uint8
+
+const (
+	// Only the aspect ratio of the pixels is known
+	ResolutionUnitNone ResolutionUnit = iota
+	ResolutionUnitInch
+	ResolutionUnitCentimeter
+)
+
+func (u ResolutionUnit) String() string {
+	switch u {
+	case ResolutionUnitNone:
+		return "None"
+	case ResolutionUnitInch:
+		return "Inch"
+	case ResolutionUnitCentimeter:
+		return "Centimeter"
+	default:
+		return "Unknown"
+	}
+}
+
+type Resolution struct {
+	// Horizontal and vertical number of pixels per unit, zero if the image does not
+	// store its resolution
+	X float64
+	Y float64
+
+	Unit ResolutionUnit
+}
+
+// DPI returns the horizontal and vertical resolution in pixels per inch. Zero is
+// returned if the resolution is unknown or only describes the aspect ratio.
+func (r Resolution) DPI() (float64, float64) {
+	switch r.Unit {
+	case ResolutionUnitInch:
+		return r.X, r.Y
+	case ResolutionUnitCentimeter:
+		return r.X * 2.54, r.Y * 2.54
+	default:
+		return 0, 0
+	}
+}
+
+// Implemented by parsers of image formats which can store their physical resolution.
+// Images without a resolution are reported as Valid with an empty resolution.
+type ResolutionParser interface {
+	GetResolution(p []byte) (Result, Resolution)
+}
+
+// resolutionPerMeter converts pixels per meter, as stored by PNG and BMP files.
+func resolutionPerMeter(x uint32, y uint32) Resolution {
+	return Resolution{X: float64(x) / 100, Y: float64(y) / 100, Unit: ResolutionUnitCentimeter}
+}
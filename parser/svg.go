@@ -0,0 +1,369 @@
+package parser
+
+import (
+	"bytes"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// https://www.w3.org/TR/SVG11/struct.html#SVGElementWidthAttribute
+// https://www.w3.org/TR/SVG11/coords.html#Units
+
+type SVGParser struct{}
+
+type SVGViewBox struct {
+	MinX   float64
+	MinY   float64
+	Width  float64
+	Height float64
+}
+
+type SVGSize struct {
+	// Size in pixels, derived from width and height or from the viewBox
+	Size ImageSize
+
+	ViewBox    SVGViewBox
+	HasViewBox bool
+
+	// Width or height is given in percent, so the size rendered depends on the
+	// container. Size is derived from the viewBox in this case, or zero if the
+	// image has no viewBox.
+	Relative bool
+}
+
+func (S SVGParser) Type() ImageType {
+	return SVG
+}
+
+func (S SVGParser) DetectType(p []byte) (r Result) {
+	result, _ := svgFindRoot(p)
+	return result
+}
+
+func (S SVGParser) GetSize(p []byte) (r Result, t ImageSize) {
+	result, size := S.GetSVGSize(p)
+	if result != Valid {
+		return result, ImageSize{}
+	}
+
+	return Valid, size.Size
+}
+
+// GetSVGSize reads the width, height and viewBox attributes of the root element.
+func (S SVGParser) GetSVGSize(p []byte) (r Result, s SVGSize) {
+	result, i := svgFindRoot(p)
+	if result != Valid {
+		return result, SVGSize{}
+	}
+
+	result, attributes := svgParseAttributes(p, i)
+	if result != Valid {
+		return result, SVGSize{}
+	}
+
+	size := SVGSize{}
+
+	if viewBox, ok := attributes["viewBox"]; ok {
+		size.ViewBox, size.HasViewBox = svgParseViewBox(viewBox)
+	}
+
+	width, widthRelative, widthOk := svgParseLength(attributes["width"])
+	height, heightRelative, heightOk := svgParseLength(attributes["height"])
+
+	widthAbsolute := widthOk && !widthRelative
+	heightAbsolute := heightOk && !heightRelative
+	size.Relative = widthRelative || heightRelative
+
+	switch {
+	case widthAbsolute && heightAbsolute:
+		// Both are given
+	case widthAbsolute && size.HasViewBox:
+		height = width * size.ViewBox.Height / size.ViewBox.Width
+	case heightAbsolute && size.HasViewBox:
+		width = height * size.ViewBox.Width / size.ViewBox.Height
+	case size.HasViewBox:
+		width = size.ViewBox.Width
+		height = size.ViewBox.Height
+	case size.Relative:
+		// The image has no size of its own
+		width, height = 0, 0
+	default:
+		return Invalid, SVGSize{}
+	}
+
+	size.Size = ImageSize{Width: uint32(math.Round(width)), Height: uint32(math.Round(height))}
+
+	return Valid, size
+}
+
+// svgFindRoot skips the byte order mark, xml declaration, processing instructions,
+// comments and the doctype and returns the offset of the root element name, which
+// has to be svg.
+func svgFindRoot(p []byte) (Result, int) {
+	byteOrderMark := []byte{'\xef', '\xbb', '\xbf'}
+
+	i := 0
+	if len(p) < len(byteOrderMark) && bytes.HasPrefix(byteOrderMark, p) {
+		return NeedMoreData, 0
+	}
+
+	if bytes.HasPrefix(p, byteOrderMark) {
+		i += len(byteOrderMark)
+	}
+
+	for {
+		i = svgSkipWhitespace(p, i)
+
+		if len(p) < i+2 {
+			return NeedMoreData, 0
+		}
+
+		if p[i] != '<' {
+			return Invalid, 0
+		}
+
+		switch p[i+1] {
+		case '?':
+			// XML declaration or processing instruction
+			end := bytes.Index(p[i+2:], []byte("?>"))
+			if end == -1 {
+				return NeedMoreData, 0
+			}
+			i += 2 + end + 2
+
+		case '!':
+			if result := svgMatchPrefix(p[i:], "<!--"); result == Valid {
+				end := bytes.Index(p[i+4:], []byte("-->"))
+				if end == -1 {
+					return NeedMoreData, 0
+				}
+				i += 4 + end + 3
+				continue
+			} else if result == NeedMoreData {
+				return NeedMoreData, 0
+			}
+
+			if result := svgMatchPrefix(p[i:], "<!DOCTYPE"); result != Valid {
+				return result, 0
+			}
+
+			result, end := svgSkipDoctype(p, i+9)
+			if result != Valid {
+				return result, 0
+			}
+			i = end
+
+		default:
+			// Root element
+			i++
+
+			end := i
+			for end < len(p) && !svgIsWhitespace(p[end]) && p[end] != '/' && p[end] != '>' {
+				end++
+			}
+
+			if end == len(p) {
+				return NeedMoreData, 0
+			}
+
+			// Allow a namespace prefix (e.g. svg:svg)
+			name := string(p[i:end])
+			if colon := strings.LastIndexByte(name, ':'); colon != -1 {
+				name = name[colon+1:]
+			}
+
+			if name != "svg" {
+				return Invalid, 0
+			}
+
+			return Valid, end
+		}
+	}
+}
+
+// svgMatchPrefix checks if p starts with prefix, as far as data is available.
+func svgMatchPrefix(p []byte, prefix string) Result {
+	if len(p) < len(prefix) {
+		if bytes.HasPrefix([]byte(prefix), p) {
+			return NeedMoreData
+		}
+		return Invalid
+	}
+
+	if string(p[:len(prefix)]) != prefix {
+		return Invalid
+	}
+
+	return Valid
+}
+
+// svgSkipDoctype returns the offset after the doctype declaration, including an
+// internal subset inside of square brackets.
+func svgSkipDoctype(p []byte, i int) (Result, int) {
+	var quote byte
+	subset := false
+
+	for ; i < len(p); i++ {
+		c := p[i]
+
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '[':
+			subset = true
+		case c == ']':
+			subset = false
+		case c == '>' && !subset:
+			return Valid, i + 1
+		}
+	}
+
+	return NeedMoreData, 0
+}
+
+// svgParseAttributes reads the attributes of the element starting at offset i,
+// until the end of the start tag is reached.
+func svgParseAttributes(p []byte, i int) (Result, map[string]string) {
+	attributes := make(map[string]string)
+
+	for {
+		i = svgSkipWhitespace(p, i)
+
+		if len(p) < i+1 {
+			return NeedMoreData, nil
+		}
+
+		if p[i] == '>' || p[i] == '/' {
+			return Valid, attributes
+		}
+
+		// Attribute name
+		start := i
+		for i < len(p) && !svgIsWhitespace(p[i]) && p[i] != '=' && p[i] != '>' && p[i] != '/' {
+			i++
+		}
+		name := string(p[start:i])
+
+		i = svgSkipWhitespace(p, i)
+		if len(p) < i+1 {
+			return NeedMoreData, nil
+		}
+
+		if p[i] != '=' || name == "" {
+			return Invalid, nil
+		}
+
+		i = svgSkipWhitespace(p, i+1)
+		if len(p) < i+1 {
+			return NeedMoreData, nil
+		}
+
+		quote := p[i]
+		if quote != '"' && quote != '\'' {
+			return Invalid, nil
+		}
+
+		end := bytes.IndexByte(p[i+1:], quote)
+		if end == -1 {
+			return NeedMoreData, nil
+		}
+
+		attributes[name] = string(p[i+1 : i+1+end])
+		i += 1 + end + 1
+	}
+}
+
+// Pixels per unit, based on 96 pixels per inch
+var svgUnits = map[string]float64{
+	"":   1,
+	"px": 1,
+	"pt": 96.0 / 72.0,
+	"pc": 96.0 / 6.0,
+	"mm": 96.0 / 25.4,
+	"cm": 96.0 / 2.54,
+	"in": 96,
+}
+
+// svgParseLength converts a length to pixels. Percentages are reported as relative.
+func svgParseLength(s string) (value float64, relative bool, ok bool) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, false, false
+	}
+
+	// Longest numeric prefix
+	end := 0
+	for end < len(s) && strings.IndexByte("+-.0123456789eE", s[end]) != -1 {
+		// An e might also be the start of the em or ex unit
+		if (s[end] == 'e' || s[end] == 'E') && end+1 < len(s) && (s[end+1] == 'm' || s[end+1] == 'x') {
+			break
+		}
+		end++
+	}
+
+	number, err := strconv.ParseFloat(s[:end], 64)
+	if err != nil || number < 0 {
+		return 0, false, false
+	}
+
+	unit := strings.ToLower(strings.TrimSpace(s[end:]))
+
+	if unit == "%" {
+		return number, true, true
+	}
+
+	factor, ok := svgUnits[unit]
+	if !ok {
+		return 0, false, false
+	}
+
+	return number * factor, false, true
+}
+
+// svgParseViewBox reads the four numbers of the viewBox attribute, separated by
+// whitespace and/or a comma.
+func svgParseViewBox(s string) (SVGViewBox, bool) {
+	fields := strings.FieldsFunc(s, func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\t' || r == '\n' || r == '\r'
+	})
+
+	if len(fields) != 4 {
+		return SVGViewBox{}, false
+	}
+
+	var values [4]float64
+	for i, field := range fields {
+		value, err := strconv.ParseFloat(field, 64)
+		if err != nil {
+			return SVGViewBox{}, false
+		}
+		values[i] = value
+	}
+
+	// Width and height have to be positive, a value of zero disables rendering
+	if values[2] <= 0 || values[3] <= 0 {
+		return SVGViewBox{}, false
+	}
+
+	return SVGViewBox{MinX: values[0], MinY: values[1], Width: values[2], Height: values[3]}, true
+}
+
+func svgSkipWhitespace(p []byte, i int) int {
+	for i < len(p) && svgIsWhitespace(p[i]) {
+		i++
+	}
+	return i
+}
+
+func svgIsWhitespace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
+
+func init() {
+	register(&SVGParser{})
+}
@@ -0,0 +1,39 @@
+package parser
+
+type TextureInfo struct {
+	Size ImageSize
+
+	// Depth of volume textures, 1 otherwise
+	Depth uint32
+
+	// Number of array layers, 1 for textures which are not arrays
+	ArrayLayers uint32
+
+	// Number of faces, 6 for cube maps and 1 otherwise
+	Faces uint32
+
+	// Number of mipmap levels, including the base level
+	MipLevels uint32
+
+	// Pixel format, only the field matching the container is set:
+	// FourCC of compressed DDS files (e.g. DXT1), DXGI_FORMAT of DDS files with a
+	// DX10 header, glInternalFormat of KTX files and VkFormat of KTX2 files
+	FourCC           string
+	DXGIFormat       uint32
+	GLInternalFormat uint32
+	VkFormat         uint32
+}
+
+// TextureParser is implemented by parsers of texture containers.
+type TextureParser interface {
+	GetTextureInfo(p []byte) (r Result, t TextureInfo)
+}
+
+// atLeastOne replaces zero values, which texture containers use to signal that a
+// dimension is not used.
+func atLeastOne(v uint32) uint32 {
+	if v == 0 {
+		return 1
+	}
+	return v
+}
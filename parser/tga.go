@@ -0,0 +1,253 @@
+package parser
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+)
+
+// Information about the tga structure can be found here:
+// https://www.dca.fee.unicamp.br/~martino/disciplinas/ea978/tgaffs.pdf
+// https://en.wikipedia.org/wiki/Truevision_TGA
+
+// TGA files do not start with a magic number, so the header fields are checked for
+// plausible values instead. TGA 2.0 files end with a footer, which can be checked
+// with DetectFooter if random access to the file is possible.
+
+type TGAParser struct{}
+
+const tgaHeaderSize = 18
+
+var tgaFooterSignature = []byte("TRUEVISION-XFILE.\x00")
+
+func (T TGAParser) Type() ImageType {
+	return TGA
+}
+
+func (T TGAParser) DetectType(p []byte) (r Result) {
+	if len(p) < 3 {
+		return NeedMoreData
+	}
+
+	if !tgaValidHeader(p) {
+		return Invalid
+	}
+
+	if len(p) < tgaHeaderSize {
+		return NeedMoreData
+	}
+
+	colorMapType := p[1]
+	imageType := p[2]
+	colorMapLength := binary.LittleEndian.Uint16(p[5:])
+	colorMapEntrySize := p[7]
+	pixelDepth := p[16]
+	descriptor := p[17]
+
+	switch colorMapType {
+	case 0:
+		// Color map specification has to be empty
+		if !bytes.Equal(p[3:8], []byte{0, 0, 0, 0, 0}) {
+			return Invalid
+		}
+	case 1:
+		if colorMapLength == 0 {
+			return Invalid
+		}
+
+		switch colorMapEntrySize {
+		case 15, 16, 24, 32:
+		default:
+			return Invalid
+		}
+	}
+
+	switch imageType {
+	case 1, 9:
+		// Color mapped images need a color map
+		if colorMapType != 1 {
+			return Invalid
+		}
+
+		if pixelDepth != 8 && pixelDepth != 16 {
+			return Invalid
+		}
+	case 2, 10:
+		switch pixelDepth {
+		case 15, 16, 24, 32:
+		default:
+			return Invalid
+		}
+	case 3, 11:
+		if pixelDepth != 8 && pixelDepth != 16 {
+			return Invalid
+		}
+	}
+
+	// The two upper bits of the descriptor are reserved, the lower four bits hold
+	// the number of alpha bits
+	if descriptor&0xc0 != 0 || descriptor&0x0f > pixelDepth {
+		return Invalid
+	}
+
+	// Width and height
+	if binary.LittleEndian.Uint16(p[12:]) == 0 || binary.LittleEndian.Uint16(p[14:]) == 0 {
+		return Invalid
+	}
+
+	return Valid
+}
+
+func (T TGAParser) GetSize(p []byte) (r Result, t ImageSize) {
+	if result := T.DetectType(p); result != Valid {
+		return result, ImageSize{}
+	}
+
+	return tgaGetSize(p)
+}
+
+// GetPixelFormat derives the pixel format from the image type, the pixel depth and
+// the number of alpha bits of the descriptor.
+func (T TGAParser) GetPixelFormat(p []byte) (r Result, f PixelFormat) {
+	if result := T.DetectType(p); result != Valid {
+		return result, PixelFormat{}
+	}
+
+	return tgaGetPixelFormat(p)
+}
+
+// DetectFooter confirms a TGA 2.0 file by the footer at the end of the file. Only
+// the basic header fields are checked, which accepts files whose header is too
+// unusual for the heuristic of DetectType.
+func (T TGAParser) DetectFooter(r io.ReaderAt, size int64) (res Result) {
+	footerSize := int64(8 + len(tgaFooterSignature))
+
+	if size < tgaHeaderSize+footerSize {
+		return Invalid
+	}
+
+	header := make([]byte, tgaHeaderSize)
+	if _, err := r.ReadAt(header, 0); err != nil {
+		return Invalid
+	}
+
+	if !tgaValidHeader(header) {
+		return Invalid
+	}
+
+	footer := make([]byte, footerSize)
+	if _, err := r.ReadAt(footer, size-footerSize); err != nil && err != io.EOF {
+		return Invalid
+	}
+
+	if !bytes.Equal(footer[8:], tgaFooterSignature) {
+		return Invalid
+	}
+
+	return Valid
+}
+
+// GetInfoFromReaderAt returns the size and the pixel format of a file confirmed by
+// DetectFooter. Like DetectFooter, only the basic header fields are checked.
+func (T TGAParser) GetInfoFromReaderAt(p []byte, r io.ReaderAt, size int64) (res Result, i ReaderAtInfo) {
+	if T.DetectFooter(r, size) != Valid {
+		return Invalid, ReaderAtInfo{}
+	}
+
+	header := make([]byte, tgaHeaderSize)
+	if _, err := r.ReadAt(header, 0); err != nil {
+		return Invalid, ReaderAtInfo{}
+	}
+
+	result, imageSize := tgaGetSize(header)
+	if result != Valid {
+		return Invalid, ReaderAtInfo{}
+	}
+
+	result, pixelFormat := tgaGetPixelFormat(header)
+	if result != Valid {
+		return Invalid, ReaderAtInfo{}
+	}
+
+	return Valid, ReaderAtInfo{Size: imageSize, Orientation: OrientationNormal, PixelFormat: pixelFormat}
+}
+
+// tgaGetSize reads the size from a header whose color map type and image type are
+// valid, without the further checks of DetectType.
+func tgaGetSize(p []byte) (Result, ImageSize) {
+	if len(p) < tgaHeaderSize {
+		return NeedMoreData, ImageSize{}
+	}
+
+	if !tgaValidHeader(p) {
+		return Invalid, ImageSize{}
+	}
+
+	width := uint32(binary.LittleEndian.Uint16(p[12:]))
+	height := uint32(binary.LittleEndian.Uint16(p[14:]))
+
+	return Valid, ImageSize{Width: width, Height: height}
+}
+
+// tgaGetPixelFormat reads the pixel format from a header whose color map type and
+// image type are valid, without the further checks of DetectType.
+func tgaGetPixelFormat(p []byte) (Result, PixelFormat) {
+	if len(p) < tgaHeaderSize {
+		return NeedMoreData, PixelFormat{}
+	}
+
+	if !tgaValidHeader(p) {
+		return Invalid, PixelFormat{}
+	}
+
+	colorMapEntrySize := p[7]
+	pixelDepth := p[16]
+	alphaBits := p[17] & 0x0f
+
+	pixelFormat := PixelFormat{Alpha: alphaBits > 0}
+
+	switch p[2] {
+	case 1, 9:
+		pixelFormat.ColorModel = ColorModelPalette
+		pixelFormat.BitDepth = pixelDepth
+		pixelFormat.Alpha = pixelFormat.Alpha || colorMapEntrySize == 32
+	case 2, 10:
+		pixelFormat.ColorModel = ColorModelRGB
+		pixelFormat.BitDepth = 8
+		if pixelDepth == 15 || pixelDepth == 16 {
+			pixelFormat.BitDepth = 5
+		}
+	case 3, 11:
+		pixelFormat.ColorModel = ColorModelGray
+		pixelFormat.BitDepth = pixelDepth
+		if alphaBits < pixelDepth {
+			pixelFormat.BitDepth -= alphaBits
+		}
+	}
+
+	pixelFormat.Channels = pixelFormatChannels(pixelFormat.ColorModel)
+	if alphaBits > 0 && pixelFormat.ColorModel != ColorModelPalette {
+		pixelFormat.Channels++
+	}
+
+	return Valid, pixelFormat
+}
+
+// tgaValidHeader checks the color map type and the image type, which are the only
+// fields with a small set of valid values.
+func tgaValidHeader(p []byte) bool {
+	if p[1] != 0 && p[1] != 1 {
+		return false
+	}
+
+	switch p[2] {
+	case 1, 2, 3, 9, 10, 11:
+		return true
+	default:
+		return false
+	}
+}
+
+func init() {
+	register(&TGAParser{})
+}
@@ -2,6 +2,7 @@ package parser
 
 import (
 	"encoding/binary"
+	"strings"
 )
 
 // https://www.fileformat.info/format/tiff/egff.htm
@@ -20,8 +21,33 @@ type TIFFInt int
 const (
 	Uint16 TIFFInt = iota
 	Uint32
+	Uint64
 )
 
+type TIFFPage struct {
+	Size ImageSize
+
+	// Bits of each sample, defaults to a single bilevel sample
+	BitsPerSample []uint16
+
+	// Compression scheme, e.g. 1 for none or 4 for CCITT Group 4 fax encoding
+	Compression uint16
+
+	// Color space, e.g. 0 for WhiteIsZero or 2 for RGB
+	PhotometricInterpretation uint16
+
+	// Bit 0 is set for reduced resolution versions of another page, e.g. thumbnails
+	NewSubfileType uint32
+
+	// Page is stored in a SubIFD of the previous full resolution page
+	SubIFD bool
+}
+
+// Reduced returns true for reduced resolution versions of another page.
+func (t TIFFPage) Reduced() bool {
+	return t.NewSubfileType&1 != 0
+}
+
 func (T TIFFParser) Type() ImageType {
 	return TIFF
 }
@@ -31,97 +57,281 @@ func (T TIFFParser) DetectType(p []byte) (r Result) {
 		return NeedMoreData
 	}
 
-	// Little endian header
-	if p[0] == 'I' && p[1] == 'I' && p[2] == '*' && p[3] == '\x00' {
+	// Little endian header, version 42 or 43 (BigTIFF)
+	if p[0] == 'I' && p[1] == 'I' && (p[2] == '*' || p[2] == '+') && p[3] == '\x00' {
 		return Valid
 	}
 
-	// Big endian header
-	if p[0] == 'M' && p[1] == 'M' && p[2] == '\x00' && p[3] == '*' {
+	// Big endian header, version 42 or 43 (BigTIFF)
+	if p[0] == 'M' && p[1] == 'M' && p[2] == '\x00' && (p[3] == '*' || p[3] == '+') {
 		return Valid
 	}
 
 	return Invalid
 }
 
+// DetectSubtype tells plain TIFF files apart from the camera raw formats based on
+// TIFF. Only the header and, if it directly follows the header, the first IFD are
+// read, so files storing their IFDs at the end are reported as TIFF right away.
+func (T TIFFParser) DetectSubtype(p []byte) (r Result, t ImageType) {
+	if result := T.DetectType(p); result != Valid {
+		return result, UnknownType
+	}
+
+	return tiffImageType(p)
+}
+
 func (T TIFFParser) GetSize(p []byte) (r Result, t ImageSize) {
 	if result := T.DetectType(p); result != Valid {
 		return result, ImageSize{}
 	}
 
-	if len(p) < 18 {
-		return NeedMoreData, ImageSize{}
+	result, header := tiffReadHeader(p)
+	if result != Valid {
+		return result, ImageSize{}
 	}
 
-	var byteOrder TIFFByteOrder
+	result, ifd := tiffReadIFD(p, header, header.offsetFirstIFD)
+	if result != Valid {
+		return result, ImageSize{}
+	}
 
-	// Detect byte order used inside tiff image
-	switch p[0] {
-	case 'I':
-		byteOrder = LittleEndian
-	case 'M':
-		byteOrder = BigEndian
-	default:
-		return Invalid, ImageSize{}
+	// ImageWidth = 256, ImageHeight = 257
+	return tiffIFDSize(p, header.byteOrder, ifd)
+}
+
+// GetPages walks the chain of IFDs and their SubIFDs and returns a page for each IFD
+// holding an image, in the order they are stored.
+func (T TIFFParser) GetPages(p []byte) (r Result, t []TIFFPage) {
+	if result := T.DetectType(p); result != Valid {
+		return result, nil
 	}
 
-	// Version
-	version := TIFFGetInt(byteOrder, Uint16, p[2:])
+	result, header := tiffReadHeader(p)
+	if result != Valid {
+		return result, nil
+	}
 
-	if version != 42 {
-		return Invalid, ImageSize{}
+	result, ifds := tiffReadIFDs(p, header)
+	if result != Valid {
+		return result, nil
 	}
 
-	// Offset of first IFD
-	offsetFirstIFD := TIFFGetInt(byteOrder, Uint32, p[4:])
+	var pages []TIFFPage
+
+	for _, ifd := range ifds {
+		result, size := tiffIFDSize(p, header.byteOrder, ifd)
+		if result == NeedMoreData {
+			return NeedMoreData, nil
+		}
+
+		if result != Valid {
+			continue
+		}
+
+		page := TIFFPage{
+			Size:          size,
+			BitsPerSample: []uint16{1},
+			Compression:   1,
+			SubIFD:        ifd.subIFD,
+		}
+
+		// BitsPerSample
+		if entry, ok := ifd.entries[258]; ok {
+			result, values := tiffEntryUints(p, header.byteOrder, entry)
+			if result == NeedMoreData {
+				return NeedMoreData, nil
+			}
 
-	if len(p) < offsetFirstIFD+2 {
-		return NeedMoreData, ImageSize{}
+			if result == Valid && len(values) > 0 {
+				page.BitsPerSample = make([]uint16, len(values))
+				for j, value := range values {
+					page.BitsPerSample[j] = uint16(value)
+				}
+			}
+		}
+
+		// Compression, PhotometricInterpretation and NewSubfileType
+		for _, tag := range []int{259, 262, 254} {
+			result, value := tiffEntryValue(p, header.byteOrder, ifd, tag)
+			if result == NeedMoreData {
+				return NeedMoreData, nil
+			}
+
+			if result != Valid {
+				continue
+			}
+
+			switch tag {
+			case 259:
+				page.Compression = uint16(value)
+			case 262:
+				page.PhotometricInterpretation = uint16(value)
+			case 254:
+				page.NewSubfileType = uint32(value)
+			}
+		}
+
+		pages = append(pages, page)
+	}
+
+	if len(pages) == 0 {
+		return Invalid, nil
+	}
+
+	return Valid, pages
+}
+
+// GetPixelFormat reads BitsPerSample, SamplesPerPixel, PhotometricInterpretation and
+// ExtraSamples of the first IFD. Extra samples holding associated or unassociated
+// alpha are reported as alpha.
+func (T TIFFParser) GetPixelFormat(p []byte) (r Result, f PixelFormat) {
+	if result := T.DetectType(p); result != Valid {
+		return result, PixelFormat{}
 	}
 
-	i := offsetFirstIFD
+	result, header := tiffReadHeader(p)
+	if result != Valid {
+		return result, PixelFormat{}
+	}
 
-	tags := make(map[int]int)
+	result, ifd := tiffReadIFD(p, header, header.offsetFirstIFD)
+	if result != Valid {
+		return result, PixelFormat{}
+	}
 
-	for {
-		// Tag entry count
-		tagEntryCount := TIFFGetInt(byteOrder, Uint16, p[i:])
+	// Default values of BitsPerSample and SamplesPerPixel, photometric
+	// interpretation has no default
+	values := map[int]int{258: 1, 277: 1, 262: -1}
 
-		if len(p) < offsetFirstIFD+2+12*tagEntryCount+4 {
-			return NeedMoreData, ImageSize{}
+	for tag := range values {
+		result, value := tiffEntryValue(p, header.byteOrder, ifd, tag)
+		if result == NeedMoreData {
+			return NeedMoreData, PixelFormat{}
 		}
 
-		i += 2
+		if result == Valid {
+			values[tag] = value
+		}
+	}
+
+	pixelFormat := PixelFormat{
+		BitDepth: uint8(values[258]),
+		Channels: uint16(values[277]),
+	}
 
-		// Go over tags
-		for j := 0; j < tagEntryCount; j++ {
-			TIFFParseTag(byteOrder, p[i:i+12], tags)
-			i += 12
+	switch values[262] {
+	case 0, 1:
+		pixelFormat.ColorModel = ColorModelGray
+	case 2:
+		pixelFormat.ColorModel = ColorModelRGB
+	case 3:
+		pixelFormat.ColorModel = ColorModelPalette
+	case 5:
+		// Separated, usually CMYK as selected by InkSet
+		pixelFormat.ColorModel = ColorModelCMYK
+	case 6:
+		pixelFormat.ColorModel = ColorModelYCbCr
+	}
+
+	if entry, ok := ifd.entries[338]; ok {
+		result, extraSamples := tiffEntryUints(p, header.byteOrder, entry)
+		if result == NeedMoreData {
+			return NeedMoreData, PixelFormat{}
 		}
 
-		// NextIFDOffset
-		nextIFDOffset := TIFFGetInt(byteOrder, Uint32, p[i:])
-		if nextIFDOffset != 0 {
-			i += nextIFDOffset
+		for _, extraSample := range extraSamples {
+			if extraSample == 1 || extraSample == 2 {
+				pixelFormat.Alpha = true
+			}
 		}
+	}
+
+	return Valid, pixelFormat
+}
 
-		break
+// GetOrientation reads the Orientation tag of the first IFD.
+func (T TIFFParser) GetOrientation(p []byte) (r Result, o Orientation) {
+	if result := T.DetectType(p); result != Valid {
+		return result, OrientationNormal
 	}
 
-	// Check if we have collected width and height tag
-	// ImageWidth = 256
-	width, ok := tags[256]
-	if !ok {
-		return Invalid, ImageSize{}
+	return exifOrientation(p, false)
+}
+
+// GetResolution reads the XResolution, YResolution and ResolutionUnit tags of the
+// first IFD.
+func (T TIFFParser) GetResolution(p []byte) (r Result, t Resolution) {
+	if result := T.DetectType(p); result != Valid {
+		return result, Resolution{}
 	}
 
-	// ImageHeight = 257
-	height, ok := tags[257]
-	if !ok {
-		return Invalid, ImageSize{}
+	return exifResolution(p, false)
+}
+
+// GetEXIF decodes the requested fields of the first IFD and the IFDs it references.
+func (T TIFFParser) GetEXIF(p []byte, fields EXIFFields) (r Result, e EXIF) {
+	if result := T.DetectType(p); result != Valid {
+		return result, EXIF{}
 	}
 
-	return Valid, ImageSize{Width: uint32(width), Height: uint32(height)}
+	return exifDecode(p, false, fields)
+}
+
+// GetXMP returns the XMP packet stored in tag 700 of the first IFD.
+func (T TIFFParser) GetXMP(p []byte) (r Result, x []byte) {
+	if result := T.DetectType(p); result != Valid {
+		return result, nil
+	}
+
+	return tiffByteTag(p, 700)
+}
+
+// GetColorProfile reads the ICC profile stored in tag 34675 of the first IFD.
+func (T TIFFParser) GetColorProfile(p []byte) (r Result, c ColorProfile) {
+	if result := T.DetectType(p); result != Valid {
+		return result, ColorProfile{}
+	}
+
+	result, profile := tiffByteTag(p, 34675)
+	if result == NeedMoreData {
+		return NeedMoreData, ColorProfile{}
+	}
+
+	if result != Valid {
+		return Valid, ColorProfile{}
+	}
+
+	return Valid, iccColorProfile(profile)
+}
+
+// tiffByteTag returns the data of a tag of the first IFD which is stored as BYTE or
+// UNDEFINED. Invalid is returned if the tag is missing.
+func tiffByteTag(p []byte, tag int) (Result, []byte) {
+	result, header := tiffReadHeader(p)
+	if result != Valid {
+		return result, nil
+	}
+
+	result, ifd := tiffReadIFD(p, header, header.offsetFirstIFD)
+	if result != Valid {
+		return result, nil
+	}
+
+	entry, ok := ifd.entries[tag]
+	if !ok || tiffTypeSize(entry.dataType) != 1 {
+		return Invalid, nil
+	}
+
+	if entry.count < 0 || entry.count > tiffMaxOffset || entry.valueOffset < 0 {
+		return Invalid, nil
+	}
+
+	if len(p) < entry.valueOffset+entry.count {
+		return NeedMoreData, nil
+	}
+
+	return Valid, p[entry.valueOffset : entry.valueOffset+entry.count]
 }
 
 func TIFFGetInt(byteOrder TIFFByteOrder, intType TIFFInt, p []byte) int {
@@ -142,11 +352,21 @@ func TIFFGetInt(byteOrder TIFFByteOrder, intType TIFFInt, p []byte) int {
 				return int(binary.BigEndian.Uint32(p[0:]))
 			}
 		}
+	case Uint64:
+		{
+			if byteOrder == LittleEndian {
+				return int(binary.LittleEndian.Uint64(p[0:]))
+			} else {
+				return int(binary.BigEndian.Uint64(p[0:]))
+			}
+		}
 	default:
 		panic("Invalid intType given")
 	}
 }
 
+// TIFFParseTag only records single SHORT or LONG values, TIFFReadIFDs decodes the
+// values of all tags.
 func TIFFParseTag(byteOrder TIFFByteOrder, p []byte, tags map[int]int) {
 
 	tagIdentifier := TIFFGetInt(byteOrder, Uint16, p[0:])
@@ -176,3 +396,479 @@ func TIFFParseTag(byteOrder TIFFByteOrder, p []byte, tags map[int]int) {
 func init() {
 	register(&TIFFParser{})
 }
+
+// Internal helpers to read IFDs of TIFF and BigTIFF files, including values stored
+// out of line, which are also needed to tell camera raw formats apart.
+
+type tiffEntry struct {
+	tag      int
+	dataType int
+	count    int
+
+	// Offset of the value, which is either inside of the entry or out of line
+	valueOffset int
+}
+
+type tiffHeader struct {
+	byteOrder TIFFByteOrder
+
+	// Magic number following the byte order mark, 43 for BigTIFF
+	version int
+
+	// BigTIFF files use 8 byte offsets and counts and 20 byte IFD entries
+	big bool
+
+	offsetFirstIFD int
+}
+
+type tiffIFD struct {
+	entries map[int]tiffEntry
+
+	// Offset of the next IFD, 0 if this is the last one
+	next int
+
+	// IFD is referenced by the SubIFDs tag of another IFD
+	subIFD bool
+}
+
+// Upper limit of IFDs to visit, which also protects against loops
+const tiffMaxIFDs = 64
+
+// tiffTypeSize returns the size in bytes of a single value of the given field type.
+func tiffTypeSize(dataType int) int {
+	switch dataType {
+	case 1, 2, 6, 7:
+		// BYTE, ASCII, SBYTE, UNDEFINED
+		return 1
+	case 3, 8:
+		// SHORT, SSHORT
+		return 2
+	case 4, 9, 11, 13:
+		// LONG, SLONG, FLOAT, IFD
+		return 4
+	case 5, 10, 12, 16, 17, 18:
+		// RATIONAL, SRATIONAL, DOUBLE, LONG8, SLONG8, IFD8
+		return 8
+	default:
+		return 0
+	}
+}
+
+// Offsets and counts larger than this are treated as invalid, which keeps the
+// arithmetic on 8 byte values of BigTIFF files from overflowing
+const tiffMaxOffset = 1 << 48
+
+// tiffReadHeader detects the byte order and reads the version and the offset of the
+// first IFD.
+func tiffReadHeader(p []byte) (Result, tiffHeader) {
+	if len(p) < 8 {
+		return NeedMoreData, tiffHeader{}
+	}
+
+	header := tiffHeader{}
+
+	switch {
+	case p[0] == 'I' && p[1] == 'I':
+		header.byteOrder = LittleEndian
+	case p[0] == 'M' && p[1] == 'M':
+		header.byteOrder = BigEndian
+	default:
+		return Invalid, tiffHeader{}
+	}
+
+	header.version = TIFFGetInt(header.byteOrder, Uint16, p[2:])
+
+	if header.version != 43 {
+		header.offsetFirstIFD = TIFFGetInt(header.byteOrder, Uint32, p[4:])
+		return Valid, header
+	}
+
+	// BigTIFF stores the byte size of offsets (always 8) and a reserved field
+	// before the offset of the first IFD
+	if len(p) < 16 {
+		return NeedMoreData, tiffHeader{}
+	}
+
+	if TIFFGetInt(header.byteOrder, Uint16, p[4:]) != 8 || TIFFGetInt(header.byteOrder, Uint16, p[6:]) != 0 {
+		return Invalid, tiffHeader{}
+	}
+
+	header.big = true
+	header.offsetFirstIFD = tiffReadOffset(p[8:], header)
+
+	return Valid, header
+}
+
+// tiffReadOffset reads an offset or count, which is 8 bytes wide in BigTIFF files.
+// Values which can not be valid are returned as -1.
+func tiffReadOffset(p []byte, header tiffHeader) int {
+	if !header.big {
+		return TIFFGetInt(header.byteOrder, Uint32, p)
+	}
+
+	value := TIFFGetInt(header.byteOrder, Uint64, p)
+	if value < 0 || value > tiffMaxOffset {
+		return -1
+	}
+
+	return value
+}
+
+// tiffReadIFD reads the entries of the IFD at the given offset.
+func tiffReadIFD(p []byte, header tiffHeader, offset int) (Result, tiffIFD) {
+	// Size of the entry count, an entry and an offset
+	countSize, entrySize, offsetSize := 2, 12, 4
+	if header.big {
+		countSize, entrySize, offsetSize = 8, 20, 8
+	}
+
+	if offset < 8 {
+		return Invalid, tiffIFD{}
+	}
+
+	if len(p) < offset+countSize {
+		return NeedMoreData, tiffIFD{}
+	}
+
+	var tagEntryCount int
+	if header.big {
+		tagEntryCount = tiffReadOffset(p[offset:], header)
+	} else {
+		tagEntryCount = TIFFGetInt(header.byteOrder, Uint16, p[offset:])
+	}
+
+	// Tags are 16 bit, so there can't be more unique entries
+	if tagEntryCount < 0 || tagEntryCount > 65536 {
+		return Invalid, tiffIFD{}
+	}
+
+	if len(p) < offset+countSize+entrySize*tagEntryCount+offsetSize {
+		return NeedMoreData, tiffIFD{}
+	}
+
+	ifd := tiffIFD{entries: make(map[int]tiffEntry)}
+
+	i := offset + countSize
+	for j := 0; j < tagEntryCount; j++ {
+		entry := tiffEntry{
+			tag:         TIFFGetInt(header.byteOrder, Uint16, p[i:]),
+			dataType:    TIFFGetInt(header.byteOrder, Uint16, p[i+2:]),
+			count:       tiffReadOffset(p[i+4:], header),
+			valueOffset: i + 4 + offsetSize,
+		}
+
+		// Values larger than the offset field are stored at an offset
+		if entry.count < 0 || tiffTypeSize(entry.dataType)*entry.count > offsetSize {
+			entry.valueOffset = tiffReadOffset(p[i+4+offsetSize:], header)
+		}
+
+		ifd.entries[entry.tag] = entry
+		i += entrySize
+	}
+
+	ifd.next = tiffReadOffset(p[i:], header)
+
+	return Valid, ifd
+}
+
+// tiffReadIFDs walks the chain of IFDs starting at the first IFD. SubIFDs follow
+// the IFD referencing them. IFDs which can not be read are skipped, unless it is the
+// first one.
+func tiffReadIFDs(p []byte, header tiffHeader) (Result, []tiffIFD) {
+	type pending struct {
+		offset int
+		subIFD bool
+	}
+
+	var ifds []tiffIFD
+
+	stack := []pending{{offset: header.offsetFirstIFD}}
+	visited := make(map[int]bool)
+
+	for len(stack) > 0 && len(visited) < tiffMaxIFDs {
+		current := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		if current.offset == 0 || visited[current.offset] {
+			continue
+		}
+		visited[current.offset] = true
+
+		result, ifd := tiffReadIFD(p, header, current.offset)
+		if result == NeedMoreData {
+			return NeedMoreData, nil
+		}
+
+		if result != Valid {
+			if current.offset == header.offsetFirstIFD {
+				return Invalid, nil
+			}
+			continue
+		}
+
+		ifd.subIFD = current.subIFD
+		ifds = append(ifds, ifd)
+
+		// The next IFD is visited after all SubIFDs, so it is pushed first
+		stack = append(stack, pending{offset: ifd.next, subIFD: current.subIFD})
+
+		// SubIFDs
+		if entry, ok := ifd.entries[330]; ok {
+			result, subIFDs := tiffEntryUints(p, header.byteOrder, entry)
+			if result == NeedMoreData {
+				return NeedMoreData, nil
+			}
+
+			for j := len(subIFDs) - 1; j >= 0; j-- {
+				stack = append(stack, pending{offset: subIFDs[j], subIFD: true})
+			}
+		}
+	}
+
+	return Valid, ifds
+}
+
+// tiffEntryUints returns the values of an entry with an unsigned integer type.
+func tiffEntryUints(p []byte, byteOrder TIFFByteOrder, entry tiffEntry) (Result, []int) {
+	size := tiffTypeSize(entry.dataType)
+
+	switch entry.dataType {
+	case 1, 3, 4, 13, 16, 18:
+	default:
+		return Invalid, nil
+	}
+
+	if entry.count < 0 || entry.count > tiffMaxOffset || entry.valueOffset < 0 {
+		return Invalid, nil
+	}
+
+	if len(p) < entry.valueOffset+size*entry.count {
+		return NeedMoreData, nil
+	}
+
+	values := make([]int, entry.count)
+	for j := range values {
+		i := entry.valueOffset + size*j
+
+		switch size {
+		case 1:
+			values[j] = int(p[i])
+		case 2:
+			values[j] = TIFFGetInt(byteOrder, Uint16, p[i:])
+		case 4:
+			values[j] = TIFFGetInt(byteOrder, Uint32, p[i:])
+		case 8:
+			values[j] = TIFFGetInt(byteOrder, Uint64, p[i:])
+		}
+	}
+
+	return Valid, values
+}
+
+// tiffEntryString returns the value of an ASCII entry without the trailing nulls.
+func tiffEntryString(p []byte, entry tiffEntry) (Result, string) {
+	if entry.dataType != 2 || entry.count < 0 || entry.count > tiffMaxOffset || entry.valueOffset < 0 {
+		return Invalid, ""
+	}
+
+	if len(p) < entry.valueOffset+entry.count {
+		return NeedMoreData, ""
+	}
+
+	value := p[entry.valueOffset : entry.valueOffset+entry.count]
+	for len(value) > 0 && value[len(value)-1] == 0 {
+		value = value[:len(value)-1]
+	}
+
+	return Valid, string(value)
+}
+
+// Raw files store their first IFD right after the header and its values and SubIFDs
+// close to it. Data beyond this limit is not waited for when detecting raw files.
+const tiffRawDetectLimit = 64 * 1024
+
+// tiffImageType decides whether a file based on TIFF is a plain TIFF file or one of
+// the camera raw formats, which are identified by their magic number or the tags of
+// the first IFD.
+func tiffImageType(p []byte) (Result, ImageType) {
+	if len(p) < 4 {
+		return NeedMoreData, UnknownType
+	}
+
+	if !(p[0] == 'I' && p[1] == 'I') && !(p[0] == 'M' && p[1] == 'M') {
+		return Invalid, UnknownType
+	}
+
+	result, header := tiffReadHeader(p)
+	if result != Valid {
+		return result, UnknownType
+	}
+
+	switch header.version {
+	case 42, 43:
+	case 0x4f52, 0x5352:
+		// IIRO, IIRS and MMOR
+		return Valid, ORF
+	case 0x55:
+		// IIU
+		return Valid, RW2
+	default:
+		return Invalid, UnknownType
+	}
+
+	// Canon stores its own magic right after the header
+	if len(p) < 10 {
+		return NeedMoreData, UnknownType
+	}
+
+	if !header.big && p[8] == 'C' && p[9] == 'R' {
+		return Valid, CR2
+	}
+
+	// Files whose first IFD is stored elsewhere, e.g. at the end, are no raw files
+	if header.big || header.offsetFirstIFD != 8 {
+		return Valid, TIFF
+	}
+
+	result, ifd := tiffReadIFD(p, header, header.offsetFirstIFD)
+	if result != Valid {
+		return result, UnknownType
+	}
+
+	// DNGVersion
+	if _, ok := ifd.entries[50706]; ok {
+		return Valid, DNG
+	}
+
+	// Make
+	entry, ok := ifd.entries[271]
+	if !ok {
+		return Valid, TIFF
+	}
+
+	if entry.valueOffset+entry.count > tiffRawDetectLimit {
+		return Valid, TIFF
+	}
+
+	result, cameraMake := tiffEntryString(p, entry)
+	if result == NeedMoreData {
+		return NeedMoreData, UnknownType
+	}
+
+	cameraMake = strings.ToUpper(cameraMake)
+
+	imageType := TIFF
+
+	switch {
+	case strings.HasPrefix(cameraMake, "NIKON"):
+		imageType = NEF
+	case strings.HasPrefix(cameraMake, "SONY"):
+		imageType = ARW
+	case strings.HasPrefix(cameraMake, "PENTAX"), strings.HasPrefix(cameraMake, "RICOH IMAGING"):
+		imageType = PEF
+	}
+
+	if imageType == TIFF {
+		return Valid, TIFF
+	}
+
+	// Scanners and cameras of these vendors write plain TIFF files as well
+	result, raw := tiffHasRawImage(p, header, ifd)
+	if result != Valid {
+		return result, UnknownType
+	}
+
+	if !raw {
+		return Valid, TIFF
+	}
+
+	return Valid, imageType
+}
+
+// tiffHasRawImage returns true if the first IFD or one of its SubIFDs holds sensor
+// data, which is either stored as color filter array or with a vendor specific raw
+// compression.
+func tiffHasRawImage(p []byte, header tiffHeader, ifd tiffIFD) (Result, bool) {
+	ifds := []tiffIFD{ifd}
+
+	// SubIFDs, as far as they are stored close to the first IFD
+	if entry, ok := ifd.entries[330]; ok && entry.valueOffset+4*entry.count <= tiffRawDetectLimit {
+		result, subIFDs := tiffEntryUints(p, header.byteOrder, entry)
+		if result == NeedMoreData {
+			return NeedMoreData, false
+		}
+
+		for _, offset := range subIFDs {
+			if offset >= tiffRawDetectLimit {
+				continue
+			}
+
+			result, subIFD := tiffReadIFD(p, header, offset)
+			if result == NeedMoreData {
+				return NeedMoreData, false
+			}
+
+			if result == Valid {
+				ifds = append(ifds, subIFD)
+			}
+		}
+	}
+
+	for _, current := range ifds {
+		// PhotometricInterpretation CFA or LinearRaw
+		result, photometric := tiffEntryValue(p, header.byteOrder, current, 262)
+		if result == NeedMoreData {
+			return NeedMoreData, false
+		}
+
+		if result == Valid && (photometric == 32803 || photometric == 34892) {
+			return Valid, true
+		}
+
+		// Compression of Sony, Nikon and Pentax raw data
+		result, compression := tiffEntryValue(p, header.byteOrder, current, 259)
+		if result == NeedMoreData {
+			return NeedMoreData, false
+		}
+
+		if result == Valid && (compression == 32767 || compression == 34713 || compression == 65535) {
+			return Valid, true
+		}
+	}
+
+	return Valid, false
+}
+
+// tiffIFDSize returns ImageWidth and ImageLength of an IFD.
+func tiffIFDSize(p []byte, byteOrder TIFFByteOrder, ifd tiffIFD) (Result, ImageSize) {
+	result, width := tiffEntryValue(p, byteOrder, ifd, 256)
+	if result != Valid {
+		return result, ImageSize{}
+	}
+
+	result, height := tiffEntryValue(p, byteOrder, ifd, 257)
+	if result != Valid {
+		return result, ImageSize{}
+	}
+
+	return Valid, ImageSize{Width: uint32(width), Height: uint32(height)}
+}
+
+// tiffEntryValue returns the first value of an entry with an unsigned integer type.
+func tiffEntryValue(p []byte, byteOrder TIFFByteOrder, ifd tiffIFD, tag int) (Result, int) {
+	entry, ok := ifd.entries[tag]
+	if !ok {
+		return Invalid, 0
+	}
+
+	result, values := tiffEntryUints(p, byteOrder, entry)
+	if result != Valid {
+		return result, 0
+	}
+
+	if len(values) == 0 {
+		return Invalid, 0
+	}
+
+	return Valid, values[0]
+}
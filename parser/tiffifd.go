@@ -0,0 +1,312 @@
+package parser
+
+import (
+	"encoding/binary"
+	"math"
+)
+
+// General reader for the IFDs of TIFF and BigTIFF files, which decodes the values of
+// all tags. Values stored out of line have to be available in the buffer, otherwise
+// NeedMoreData is returned.
+
+type TIFFFieldType uint16
+
+const (
+	TIFFTypeByte      TIFFFieldType = 1
+	TIFFTypeASCII     TIFFFieldType = 2
+	TIFFTypeShort     TIFFFieldType = 3
+	TIFFTypeLong      TIFFFieldType = 4
+	TIFFTypeRational  TIFFFieldType = 5
+	TIFFTypeSByte     TIFFFieldType = 6
+	TIFFTypeUndefined TIFFFieldType = 7
+	TIFFTypeSShort    TIFFFieldType = 8
+	TIFFTypeSLong     TIFFFieldType = 9
+	TIFFTypeSRational TIFFFieldType = 10
+	TIFFTypeFloat     TIFFFieldType = 11
+	TIFFTypeDouble    TIFFFieldType = 12
+	TIFFTypeIFD       TIFFFieldType = 13
+	TIFFTypeLong8     TIFFFieldType = 16
+	TIFFTypeSLong8    TIFFFieldType = 17
+	TIFFTypeIFD8      TIFFFieldType = 18
+)
+
+func (t TIFFFieldType) String() string {
+	switch t {
+	case TIFFTypeByte:
+		return "BYTE"
+	case TIFFTypeASCII:
+		return "ASCII"
+	case TIFFTypeShort:
+		return "SHORT"
+	case TIFFTypeLong:
+		return "LONG"
+	case TIFFTypeRational:
+		return "RATIONAL"
+	case TIFFTypeSByte:
+		return "SBYTE"
+	case TIFFTypeUndefined:
+		return "UNDEFINED"
+	case TIFFTypeSShort:
+		return "SSHORT"
+	case TIFFTypeSLong:
+		return "SLONG"
+	case TIFFTypeSRational:
+		return "SRATIONAL"
+	case TIFFTypeFloat:
+		return "FLOAT"
+	case TIFFTypeDouble:
+		return "DOUBLE"
+	case TIFFTypeIFD:
+		return "IFD"
+	case TIFFTypeLong8:
+		return "LONG8"
+	case TIFFTypeSLong8:
+		return "SLONG8"
+	case TIFFTypeIFD8:
+		return "IFD8"
+	default:
+		return "Unknown"
+	}
+}
+
+type TIFFRational struct {
+	Numerator   int64
+	Denominator int64
+}
+
+// Float returns the value of the fraction, or 0 if the denominator is 0.
+func (r TIFFRational) Float() float64 {
+	if r.Denominator == 0 {
+		return 0
+	}
+
+	return float64(r.Numerator) / float64(r.Denominator)
+}
+
+type TIFFTag struct {
+	ID    uint16
+	Type  TIFFFieldType
+	Count int
+
+	// Decoded values, only the field matching the type is set. Tags with an
+	// unknown type have no values.
+	Uints     []uint64       // BYTE, SHORT, LONG, IFD, LONG8, IFD8
+	Ints      []int64        // SBYTE, SSHORT, SLONG, SLONG8
+	Rationals []TIFFRational // RATIONAL, SRATIONAL
+	Floats    []float64      // FLOAT, DOUBLE
+	String    string         // ASCII, without the trailing nulls
+	Bytes     []byte         // UNDEFINED
+}
+
+// Uint returns the first value of an unsigned integer tag.
+func (t TIFFTag) Uint() (uint64, bool) {
+	if len(t.Uints) > 0 {
+		return t.Uints[0], true
+	}
+
+	if len(t.Ints) > 0 && t.Ints[0] >= 0 {
+		return uint64(t.Ints[0]), true
+	}
+
+	return 0, false
+}
+
+// Float returns the first value of any numeric tag as float.
+func (t TIFFTag) Float() (float64, bool) {
+	switch {
+	case len(t.Uints) > 0:
+		return float64(t.Uints[0]), true
+	case len(t.Ints) > 0:
+		return float64(t.Ints[0]), true
+	case len(t.Rationals) > 0:
+		return t.Rationals[0].Float(), true
+	case len(t.Floats) > 0:
+		return t.Floats[0], true
+	default:
+		return 0, false
+	}
+}
+
+type TIFFIFD struct {
+	Tags map[uint16]TIFFTag
+
+	// IFD is referenced by the SubIFDs tag of another IFD
+	SubIFD bool
+}
+
+// GetIFDs reads all IFDs of the file, including SubIFDs, with the values of all tags.
+func (T TIFFParser) GetIFDs(p []byte) (r Result, i []TIFFIFD) {
+	if result := T.DetectType(p); result != Valid {
+		return result, nil
+	}
+
+	return TIFFReadIFDs(p)
+}
+
+// TIFFReadIFDs reads all IFDs of TIFF data starting with the byte order mark, e.g.
+// a TIFF file or the EXIF data of other formats. The IFD chain is followed first,
+// SubIFDs are stored after the IFD referencing them.
+func TIFFReadIFDs(p []byte) (Result, []TIFFIFD) {
+	result, header := tiffReadHeader(p)
+	if result != Valid {
+		return result, nil
+	}
+
+	switch header.version {
+	case 42, 43:
+	default:
+		return Invalid, nil
+	}
+
+	result, ifds := tiffReadIFDs(p, header)
+	if result != Valid {
+		return result, nil
+	}
+
+	decoded := make([]TIFFIFD, len(ifds))
+
+	for j, ifd := range ifds {
+		result, decoded[j] = tiffDecodeIFD(p, header.byteOrder, ifd)
+		if result != Valid {
+			return result, nil
+		}
+	}
+
+	return Valid, decoded
+}
+
+// tiffDecodeIFD decodes the values of all entries of an IFD.
+func tiffDecodeIFD(p []byte, byteOrder TIFFByteOrder, ifd tiffIFD) (Result, TIFFIFD) {
+	decoded := TIFFIFD{Tags: make(map[uint16]TIFFTag), SubIFD: ifd.subIFD}
+
+	for _, entry := range ifd.entries {
+		result, tag := tiffDecodeEntry(p, byteOrder, entry)
+		if result == NeedMoreData {
+			return NeedMoreData, TIFFIFD{}
+		}
+
+		if result != Valid {
+			continue
+		}
+
+		decoded.Tags[tag.ID] = tag
+	}
+
+	return Valid, decoded
+}
+
+// tiffDecodeEntry decodes the values of an entry according to its field type.
+func tiffDecodeEntry(p []byte, byteOrder TIFFByteOrder, entry tiffEntry) (Result, TIFFTag) {
+	tag := TIFFTag{ID: uint16(entry.tag), Type: TIFFFieldType(entry.dataType), Count: entry.count}
+
+	size := tiffTypeSize(entry.dataType)
+	if size == 0 {
+		return Valid, tag
+	}
+
+	if entry.count < 0 || entry.count > tiffMaxOffset || entry.valueOffset < 0 {
+		return Invalid, TIFFTag{}
+	}
+
+	if len(p) < entry.valueOffset+size*entry.count {
+		return NeedMoreData, TIFFTag{}
+	}
+
+	data := p[entry.valueOffset : entry.valueOffset+size*entry.count]
+
+	var order binary.ByteOrder = binary.LittleEndian
+	if byteOrder == BigEndian {
+		order = binary.BigEndian
+	}
+
+	switch tag.Type {
+	case TIFFTypeByte:
+		tag.Uints = make([]uint64, entry.count)
+		for j := range tag.Uints {
+			tag.Uints[j] = uint64(data[j])
+		}
+
+	case TIFFTypeASCII:
+		for len(data) > 0 && data[len(data)-1] == 0 {
+			data = data[:len(data)-1]
+		}
+		tag.String = string(data)
+
+	case TIFFTypeShort:
+		tag.Uints = make([]uint64, entry.count)
+		for j := range tag.Uints {
+			tag.Uints[j] = uint64(order.Uint16(data[2*j:]))
+		}
+
+	case TIFFTypeLong, TIFFTypeIFD:
+		tag.Uints = make([]uint64, entry.count)
+		for j := range tag.Uints {
+			tag.Uints[j] = uint64(order.Uint32(data[4*j:]))
+		}
+
+	case TIFFTypeLong8, TIFFTypeIFD8:
+		tag.Uints = make([]uint64, entry.count)
+		for j := range tag.Uints {
+			tag.Uints[j] = order.Uint64(data[8*j:])
+		}
+
+	case TIFFTypeSByte:
+		tag.Ints = make([]int64, entry.count)
+		for j := range tag.Ints {
+			tag.Ints[j] = int64(int8(data[j]))
+		}
+
+	case TIFFTypeUndefined:
+		tag.Bytes = append([]byte(nil), data...)
+
+	case TIFFTypeSShort:
+		tag.Ints = make([]int64, entry.count)
+		for j := range tag.Ints {
+			tag.Ints[j] = int64(int16(order.Uint16(data[2*j:])))
+		}
+
+	case TIFFTypeSLong:
+		tag.Ints = make([]int64, entry.count)
+		for j := range tag.Ints {
+			tag.Ints[j] = int64(int32(order.Uint32(data[4*j:])))
+		}
+
+	case TIFFTypeSLong8:
+		tag.Ints = make([]int64, entry.count)
+		for j := range tag.Ints {
+			tag.Ints[j] = int64(order.Uint64(data[8*j:]))
+		}
+
+	case TIFFTypeRational:
+		tag.Rationals = make([]TIFFRational, entry.count)
+		for j := range tag.Rationals {
+			tag.Rationals[j] = TIFFRational{
+				Numerator:   int64(order.Uint32(data[8*j:])),
+				Denominator: int64(order.Uint32(data[8*j+4:])),
+			}
+		}
+
+	case TIFFTypeSRational:
+		tag.Rationals = make([]TIFFRational, entry.count)
+		for j := range tag.Rationals {
+			tag.Rationals[j] = TIFFRational{
+				Numerator:   int64(int32(order.Uint32(data[8*j:]))),
+				Denominator: int64(int32(order.Uint32(data[8*j+4:]))),
+			}
+		}
+
+	case TIFFTypeFloat:
+		tag.Floats = make([]float64, entry.count)
+		for j := range tag.Floats {
+			tag.Floats[j] = float64(math.Float32frombits(order.Uint32(data[4*j:])))
+		}
+
+	case TIFFTypeDouble:
+		tag.Floats = make([]float64, entry.count)
+		for j := range tag.Floats {
+			tag.Floats[j] = math.Float64frombits(order.Uint64(data[8*j:]))
+		}
+	}
+
+	return Valid, tag
+}
@@ -1,5 +1,11 @@
 package parser
 
+import (
+	"encoding/binary"
+	"io"
+	"time"
+)
+
 // https://datatracker.ietf.org/doc/draft-zern-webp/
 
 type WEBPParser struct{}
@@ -61,6 +67,362 @@ func (W WEBPParser) GetSize(p []byte) (r Result, t ImageSize) {
 	return Invalid, ImageSize{}
 }
 
+// GetPixelFormat returns the pixel format of the image data, or of the first frame
+// of animations. Lossy image data is stored as YCbCr, lossless image data as RGB.
+// Images in the extended format report alpha by the flag of the VP8X chunk.
+func (W WEBPParser) GetPixelFormat(p []byte) (r Result, f PixelFormat) {
+	if result := W.DetectType(p); result != Valid {
+		return result, PixelFormat{}
+	}
+
+	if len(p) < 30 {
+		return NeedMoreData, PixelFormat{}
+	}
+
+	pixelFormat := PixelFormat{BitDepth: 8}
+
+	switch string(p[12:16]) {
+	case "VP8 ":
+		pixelFormat.ColorModel = ColorModelYCbCr
+	case "VP8L":
+		// Signature, followed by 14 bits each for width and height and the
+		// alpha_is_used bit
+		pixelFormat.ColorModel = ColorModelRGB
+		pixelFormat.Alpha = p[24]&0x10 != 0
+	case "VP8X":
+		pixelFormat.Alpha = p[20]&0x10 != 0
+
+		result, colorModel := webpColorModel(p)
+		if result != Valid {
+			return result, PixelFormat{}
+		}
+		pixelFormat.ColorModel = colorModel
+	default:
+		return Invalid, PixelFormat{}
+	}
+
+	pixelFormat.Channels = pixelFormatChannels(pixelFormat.ColorModel)
+	if pixelFormat.Alpha {
+		pixelFormat.Channels++
+	}
+
+	return Valid, pixelFormat
+}
+
+// webpColorModel returns the color model of the first VP8 or VP8L chunk of the
+// extended format, without reading the image data itself.
+func webpColorModel(p []byte) (Result, ColorModel) {
+	riffEnd := 8 + int(binary.LittleEndian.Uint32(p[4:]))
+
+	for i := 12; i+8 <= riffEnd; {
+		if len(p) < i+8 {
+			return NeedMoreData, ColorModelUnknown
+		}
+
+		switch string(p[i : i+4]) {
+		case "VP8 ":
+			return Valid, ColorModelYCbCr
+		case "VP8L":
+			return Valid, ColorModelRGB
+		case "ANMF":
+			// Frame header in front of the chunks of the frame
+			i += 8 + 16
+			continue
+		}
+
+		chunkSize := int(binary.LittleEndian.Uint32(p[i+4:]))
+		end := i + 8 + chunkSize
+		if end < i {
+			return Invalid, ColorModelUnknown
+		}
+
+		// Chunks are padded to an even size
+		i = end + chunkSize%2
+	}
+
+	return Invalid, ColorModelUnknown
+}
+
+// GetOrientation reads the Orientation tag of the EXIF chunk. The chunk is stored
+// after the image data, so the whole file is needed if the extended format header
+// announces EXIF data. GetInfoFromReaderAt skips the image data if random access is
+// possible.
+func (W WEBPParser) GetOrientation(p []byte) (r Result, o Orientation) {
+	if result := W.DetectType(p); result != Valid {
+		return result, OrientationNormal
+	}
+
+	if len(p) < 21 {
+		return NeedMoreData, OrientationNormal
+	}
+
+	// VP8X with the EXIF flag set
+	if string(p[12:16]) != "VP8X" || p[20]&0x08 == 0 {
+		return Valid, OrientationNormal
+	}
+
+	result, data := webpFindChunk(p, "EXIF")
+	if result == NeedMoreData {
+		return NeedMoreData, OrientationNormal
+	}
+
+	if result != Valid {
+		return Valid, OrientationNormal
+	}
+
+	result, orientation := exifOrientation(exifTrimIdentifier(data), true)
+	if result != Valid {
+		return Valid, OrientationNormal
+	}
+
+	return Valid, orientation
+}
+
+// GetInfoFromReaderAt reads the size and the pixel format from the start p of the
+// file and the orientation from the EXIF chunk, whose chunk headers are read directly
+// from r to skip the image data in front of it.
+func (W WEBPParser) GetInfoFromReaderAt(p []byte, r io.ReaderAt, size int64) (res Result, i ReaderAtInfo) {
+	result, imageSize := W.GetSize(p)
+	if result != Valid {
+		return result, ReaderAtInfo{}
+	}
+
+	result, pixelFormat := W.GetPixelFormat(p)
+	if result != Valid {
+		return result, ReaderAtInfo{}
+	}
+
+	info := ReaderAtInfo{Size: imageSize, Orientation: OrientationNormal, PixelFormat: pixelFormat}
+
+	// VP8X with the EXIF flag set
+	if string(p[12:16]) != "VP8X" || p[20]&0x08 == 0 {
+		return Valid, info
+	}
+
+	offset, length := webpFindChunkAt(r, size, binary.LittleEndian.Uint32(p[4:]), "EXIF")
+	if offset == 0 {
+		return Valid, info
+	}
+
+	complete := true
+	if length > exifMaxOrientationSize {
+		length, complete = exifMaxOrientationSize, false
+	}
+
+	data := make([]byte, length)
+	if _, err := r.ReadAt(data, offset); err != nil && err != io.EOF {
+		return Valid, info
+	}
+
+	if result, orientation := exifOrientation(exifTrimIdentifier(data), complete); result == Valid {
+		info.Orientation = orientation
+	}
+
+	return Valid, info
+}
+
+// GetEXIF decodes the requested fields of the EXIF chunk, which is stored after the
+// image data.
+func (W WEBPParser) GetEXIF(p []byte, fields EXIFFields) (r Result, e EXIF) {
+	if result := W.DetectType(p); result != Valid {
+		return result, EXIF{}
+	}
+
+	if len(p) < 21 {
+		return NeedMoreData, EXIF{}
+	}
+
+	// VP8X with the EXIF flag set
+	if string(p[12:16]) != "VP8X" || p[20]&0x08 == 0 {
+		return Invalid, EXIF{}
+	}
+
+	result, data := webpFindChunk(p, "EXIF")
+	if result != Valid {
+		return result, EXIF{}
+	}
+
+	return exifDecode(exifTrimIdentifier(data), true, fields)
+}
+
+// GetXMP returns the XMP packet stored in the XMP chunk after the image data.
+func (W WEBPParser) GetXMP(p []byte) (r Result, x []byte) {
+	if result := W.DetectType(p); result != Valid {
+		return result, nil
+	}
+
+	if len(p) < 21 {
+		return NeedMoreData, nil
+	}
+
+	// VP8X with the XMP flag set
+	if string(p[12:16]) != "VP8X" || p[20]&0x04 == 0 {
+		return Invalid, nil
+	}
+
+	return webpFindChunk(p, "XMP ")
+}
+
+// GetColorProfile reads the ICC profile of the ICCP chunk, which directly follows
+// the VP8X chunk.
+func (W WEBPParser) GetColorProfile(p []byte) (r Result, c ColorProfile) {
+	if result := W.DetectType(p); result != Valid {
+		return result, ColorProfile{}
+	}
+
+	if len(p) < 21 {
+		return NeedMoreData, ColorProfile{}
+	}
+
+	// VP8X with the ICC flag set
+	if string(p[12:16]) != "VP8X" || p[20]&0x20 == 0 {
+		return Valid, ColorProfile{}
+	}
+
+	result, data := webpFindChunk(p, "ICCP")
+	if result != Valid {
+		return result, ColorProfile{}
+	}
+
+	return Valid, iccColorProfile(data)
+}
+
+// GetAnimation reads the loop count of the ANIM chunk and the duration of each ANMF
+// chunk. Only the frame headers are read, not the frame data following them.
+func (W WEBPParser) GetAnimation(p []byte, maxFrames int) (r Result, a Animation) {
+	if result := W.DetectType(p); result != Valid {
+		return result, Animation{}
+	}
+
+	if len(p) < 21 {
+		return NeedMoreData, Animation{}
+	}
+
+	// VP8X with the animation flag set
+	if string(p[12:16]) != "VP8X" || p[20]&0x02 == 0 {
+		return Valid, Animation{Frames: 1}
+	}
+
+	animation := Animation{Animated: true}
+
+	// The RIFF size covers everything after the size field
+	riffEnd := 8 + int(binary.LittleEndian.Uint32(p[4:]))
+
+	for i := 12; i+8 <= riffEnd; {
+		if len(p) < i+8 {
+			return NeedMoreData, Animation{}
+		}
+
+		chunkSize := int(binary.LittleEndian.Uint32(p[i+4:]))
+		end := i + 8 + chunkSize
+		if end < i {
+			return Invalid, Animation{}
+		}
+
+		switch string(p[i : i+4]) {
+		case "ANIM":
+			// Background color and loop count
+			if chunkSize < 6 {
+				return Invalid, Animation{}
+			}
+
+			if len(p) < i+8+6 {
+				return NeedMoreData, Animation{}
+			}
+
+			animation.Loops = int(binary.LittleEndian.Uint16(p[i+8+4:]))
+		case "ANMF":
+			// Offset, size and the duration in milliseconds, each stored in 24 bits
+			if chunkSize < 16 {
+				return Invalid, Animation{}
+			}
+
+			if len(p) < i+8+16 {
+				return NeedMoreData, Animation{}
+			}
+
+			j := i + 8 + 12
+			duration := time.Duration(uint32(p[j])|uint32(p[j+1])<<8|uint32(p[j+2])<<16) * time.Millisecond
+
+			if animationAddFrame(&animation, duration, maxFrames) {
+				return Valid, animation
+			}
+		}
+
+		// Chunks are padded to an even size
+		i = end + chunkSize%2
+	}
+
+	return Valid, animation
+}
+
+// webpFindChunk returns the data of the first chunk with the given FourCC. Other
+// chunks are skipped by their header, so their data does not have to be read.
+func webpFindChunk(p []byte, fourCC string) (Result, []byte) {
+	// The RIFF size covers everything after the size field
+	riffEnd := 8 + int(binary.LittleEndian.Uint32(p[4:]))
+
+	for i := 12; i+8 <= riffEnd; {
+		if len(p) < i+8 {
+			return NeedMoreData, nil
+		}
+
+		chunkSize := int(binary.LittleEndian.Uint32(p[i+4:]))
+		end := i + 8 + chunkSize
+		if end < i {
+			return Invalid, nil
+		}
+
+		if string(p[i:i+4]) == fourCC {
+			if len(p) < end {
+				return NeedMoreData, nil
+			}
+
+			return Valid, p[i+8 : end]
+		}
+
+		// Chunks are padded to an even size
+		i = end + chunkSize%2
+	}
+
+	return Invalid, nil
+}
+
+// webpFindChunkAt returns the offset and size of the data of the first chunk with
+// the given FourCC, reading only the chunk headers from r. Zero is returned if there
+// is no such chunk.
+func webpFindChunkAt(r io.ReaderAt, size int64, riffSize uint32, fourCC string) (int64, int64) {
+	// The RIFF size covers everything after the size field
+	riffEnd := 8 + int64(riffSize)
+	if riffEnd > size {
+		riffEnd = size
+	}
+
+	header := make([]byte, 8)
+
+	for i := int64(12); i+8 <= riffEnd; {
+		if _, err := r.ReadAt(header, i); err != nil {
+			return 0, 0
+		}
+
+		chunkSize := int64(binary.LittleEndian.Uint32(header[4:]))
+		end := i + 8 + chunkSize
+
+		if string(header[:4]) == fourCC {
+			if end > riffEnd {
+				return 0, 0
+			}
+
+			return i + 8, chunkSize
+		}
+
+		// Chunks are padded to an even size
+		i = end + chunkSize%2
+	}
+
+	return 0, 0
+}
+
 func init() {
 	register(&WEBPParser{})
 }
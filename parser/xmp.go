@@ -0,0 +1,227 @@
+package parser
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Information about the xmp structure can be found here:
+// https://github.com/adobe/XMP-Toolkit-SDK/blob/main/docs/XMPSpecificationPart1.pdf
+// https://github.com/adobe/XMP-Toolkit-SDK/blob/main/docs/XMPSpecificationPart3.pdf
+
+// Implemented by parsers of image formats which can contain an XMP packet. Invalid is
+// returned if the image does not contain one.
+type XMPParser interface {
+	GetXMP(p []byte) (Result, []byte)
+}
+
+// Implemented by parsers of image formats which split large XMP data into a standard
+// packet and an extended packet, which is referenced by xmpNote:HasExtendedXMP of the
+// standard packet. Invalid is returned if the image does not contain one.
+type ExtendedXMPParser interface {
+	GetExtendedXMP(p []byte) (Result, []byte)
+}
+
+// Common properties of the Dublin Core and XMP basic namespaces
+type XMP struct {
+	// dc:title, dc:description and dc:rights, in the default language
+	Title       string
+	Description string
+	Rights      string
+
+	// dc:creator
+	Creator []string
+
+	// dc:subject, which holds the keywords
+	Subject []string
+
+	// xmp:Rating, -1 for rejected and 0 to 5 stars
+	Rating float64
+
+	// xmp:Label, xmp:CreatorTool, xmp:CreateDate and xmp:ModifyDate
+	Label       string
+	CreatorTool string
+	CreateDate  string
+	ModifyDate  string
+}
+
+const (
+	xmpNamespaceRDF = "http://www.w3.org/1999/02/22-rdf-syntax-ns#"
+	xmpNamespaceDC  = "http://purl.org/dc/elements/1.1/"
+	xmpNamespaceXMP = "http://ns.adobe.com/xap/1.0/"
+	xmpNamespaceXML = "http://www.w3.org/XML/1998/namespace"
+)
+
+// ParseXMP reads the properties of the XMP struct from an XMP packet. Properties can
+// be stored as attributes of rdf:Description or as elements, arrays are read from
+// their rdf:li items.
+func ParseXMP(packet []byte) (XMP, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(packet))
+
+	xmp := XMP{}
+
+	// Property currently read, its items and the index of the item in the default
+	// language of alternatives
+	var property xml.Name
+	var items []string
+	var text strings.Builder
+	defaultIndex := -1
+	itemDefault := false
+	depth := 0
+
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			return XMP{}, err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			if property.Local == "" {
+				if t.Name.Space == xmpNamespaceRDF && t.Name.Local == "Description" {
+					for _, attr := range t.Attr {
+						xmpSetProperty(&xmp, attr.Name, []string{attr.Value})
+					}
+				} else if xmpIsProperty(t.Name) {
+					property = t.Name
+					items, defaultIndex, depth = nil, -1, 0
+					text.Reset()
+				}
+				continue
+			}
+
+			depth++
+
+			if t.Name.Space == xmpNamespaceRDF && t.Name.Local == "li" {
+				text.Reset()
+				itemDefault = false
+
+				for _, attr := range t.Attr {
+					if attr.Name.Space == xmpNamespaceXML && attr.Name.Local == "lang" && attr.Value == "x-default" {
+						itemDefault = true
+					}
+				}
+			}
+
+		case xml.CharData:
+			if property.Local != "" {
+				text.Write(t)
+			}
+
+		case xml.EndElement:
+			if property.Local == "" {
+				continue
+			}
+
+			if depth > 0 {
+				depth--
+
+				if t.Name.Space == xmpNamespaceRDF && t.Name.Local == "li" {
+					if itemDefault && defaultIndex == -1 {
+						defaultIndex = len(items)
+					}
+					items = append(items, strings.TrimSpace(text.String()))
+				}
+				continue
+			}
+
+			// Simple values are stored directly inside of the property element
+			if items == nil {
+				items = []string{strings.TrimSpace(text.String())}
+			}
+
+			if defaultIndex > 0 {
+				items[0], items[defaultIndex] = items[defaultIndex], items[0]
+			}
+
+			xmpSetProperty(&xmp, property, items)
+			property = xml.Name{}
+		}
+	}
+
+	return xmp, nil
+}
+
+func xmpIsProperty(name xml.Name) bool {
+	switch name.Space {
+	case xmpNamespaceDC:
+		switch name.Local {
+		case "title", "description", "rights", "creator", "subject":
+			return true
+		}
+	case xmpNamespaceXMP:
+		switch name.Local {
+		case "Rating", "Label", "CreatorTool", "CreateDate", "ModifyDate":
+			return true
+		}
+	}
+
+	return false
+}
+
+func xmpSetProperty(xmp *XMP, name xml.Name, items []string) {
+	if !xmpIsProperty(name) || len(items) == 0 {
+		return
+	}
+
+	switch name.Local {
+	case "title":
+		xmp.Title = items[0]
+	case "description":
+		xmp.Description = items[0]
+	case "rights":
+		xmp.Rights = items[0]
+	case "creator":
+		xmp.Creator = items
+	case "subject":
+		xmp.Subject = items
+	case "Rating":
+		if rating, err := strconv.ParseFloat(items[0], 64); err == nil {
+			xmp.Rating = rating
+		}
+	case "Label":
+		xmp.Label = items[0]
+	case "CreatorTool":
+		xmp.CreatorTool = items[0]
+	case "CreateDate":
+		xmp.CreateDate = items[0]
+	case "ModifyDate":
+		xmp.ModifyDate = items[0]
+	}
+}
+
+// xmpExtendedGUID returns the value of xmpNote:HasExtendedXMP, which is stored as
+// attribute or element, or an empty string if the packet has no extension.
+func xmpExtendedGUID(packet []byte) string {
+	i := bytes.Index(packet, []byte("HasExtendedXMP"))
+	if i == -1 {
+		return ""
+	}
+
+	end := bytes.IndexAny(packet[i:], "\"'>")
+	if end == -1 {
+		return ""
+	}
+
+	i += end + 1
+	if len(packet) < i+32 {
+		return ""
+	}
+
+	// GUID is the MD5 digest of the extended packet as 32 hexadecimal digits
+	guid := string(packet[i : i+32])
+	for _, c := range guid {
+		if !strings.ContainsRune("0123456789ABCDEFabcdef", c) {
+			return ""
+		}
+	}
+
+	return guid
+}